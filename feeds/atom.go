@@ -0,0 +1,188 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// atomFeed is an Atom 1.0 feed, as specified in RFC 4287.
+type atomFeed struct {
+	XMLName   xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang      string       `xml:"xml:lang,attr,omitempty"`
+	HistoryNS string       `xml:"xmlns:fh,attr,omitempty"`
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle,omitempty"`
+	Updated   string       `xml:"updated"`
+	Links     []atomLink   `xml:"link"`
+	Author    *atomPerson  `xml:"author,omitempty"`
+	Rights    string       `xml:"rights,omitempty"`
+	Generator string       `xml:"generator,omitempty"`
+	Archive   *struct{}    `xml:"fh:archive"`
+	Entries   []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int    `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Atom returns the data as an Atom feed. The parameter feedURL is the URL of
+// the feed without any page selection. It is also the identifier of the feed,
+// which must be different for every feed, even if all feeds link to the same
+// Zettelstore.
+func (fd *feedData) Atom(feedURL string) *atomFeed {
+	updated := fd.LastModified()
+	feed := atomFeed{
+		Lang:     fd.Language,
+		ID:       atomFeedID(fd.Name, feedURL),
+		Title:    fd.Title,
+		Subtitle: fd.Description,
+		Updated:  atomDate(updated),
+		Links: []atomLink{
+			{Href: fd.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Author:    makeAtomPerson(fd.Author, fd.ManagingEditor, fd.Title),
+		Rights:    fd.Copyright,
+		Generator: "Zettel Feeds",
+		Entries:   make([]*atomEntry, 0, len(fd.Items)),
+	}
+	if feedURL != "" {
+		const typ = "application/atom+xml"
+		feed.Links = append(feed.Links, atomLink{Href: fd.Page.URL(feedURL), Rel: "self", Type: typ})
+		for _, pl := range fd.PageLinks() {
+			feed.Links = append(feed.Links, atomLink{Href: pl.Page.URL(feedURL), Rel: pl.Rel, Type: typ})
+		}
+		if fd.Hub != "" && fd.Page == currentPage {
+			feed.Links = append(feed.Links, atomLink{Href: fd.Hub, Rel: "hub"})
+		}
+	}
+	if fd.Page.Archive {
+		feed.HistoryNS = historyNamespace
+		feed.Archive = &struct{}{}
+	}
+	for _, item := range fd.Items {
+		entryUpdated := item.Updated
+		if entryUpdated.IsZero() {
+			entryUpdated = updated
+		}
+		entry := atomEntry{
+			ID:      item.Link,
+			Title:   item.Title,
+			Updated: atomDate(entryUpdated),
+			Links:   []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+		}
+		if !item.Published.IsZero() {
+			entry.Published = atomDate(item.Published)
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Text: item.Content}
+		}
+		for _, fe := range item.Enclosures {
+			entry.Links = append(entry.Links, atomLink{Href: fe.URL, Rel: "enclosure", Type: fe.Type, Length: fe.Length})
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, &entry)
+	}
+	return &feed
+}
+
+// atomFeedID returns the identifier of a feed: its URL, or a URN based on its
+// name, if the URL is not known.
+func atomFeedID(name, feedURL string) string {
+	if feedURL != "" {
+		return feedURL
+	}
+	return "urn:zettel-feeds:" + url.PathEscape(name)
+}
+
+// makeAtomPerson returns the person responsible for a feed. The email address
+// may be given in RSS syntax, i.e. "email (name)".
+func makeAtomPerson(name, email, defaultName string) *atomPerson {
+	if email != "" {
+		if addr, comment, found := cutRSSPerson(email); found {
+			email = addr
+			if name == "" {
+				name = comment
+			}
+		}
+	}
+	if name == "" {
+		name = defaultName
+	}
+	if name == "" {
+		return nil
+	}
+	return &atomPerson{Name: name, Email: email}
+}
+
+func cutRSSPerson(s string) (string, string, bool) {
+	addr, comment, found := strings.Cut(s, "(")
+	if !found || !strings.HasSuffix(comment, ")") {
+		return s, "", false
+	}
+	return strings.TrimSpace(addr), strings.TrimSpace(strings.TrimSuffix(comment, ")")), true
+}
+
+func atomDate(ts time.Time) string { return ts.Format(time.RFC3339) }
+
+// Write the Atom feed as XML.
+func (af *atomFeed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(af); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,54 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// credentials returns the base URL of the Zettelstore without user
+// information, together with user name and password. Credentials given in
+// the feed definition take precedence over those within the URL.
+func (fi *feedInfo) credentials() (*url.URL, string, string, error) {
+	u, err := url.Parse(fi.URL)
+	if err != nil {
+		return nil, "", "", err
+	}
+	username, password := "", ""
+	if uinfo := u.User; uinfo != nil {
+		username = uinfo.Username()
+		if pw, ok := uinfo.Password(); ok {
+			password = pw
+		}
+		u.User = nil
+	}
+	if fi.Username != "" {
+		username = expandEnv(fi.Username)
+	}
+	if fi.Password != "" {
+		password = expandEnv(fi.Password)
+	}
+	return u, username, password, nil
+}
+
+// expandEnv returns the value of an environment variable, if the given value
+// starts with "$". This allows to store secrets outside the feed definitions.
+func expandEnv(val string) string {
+	if name, found := strings.CutPrefix(val, "$"); found {
+		return os.Getenv(name)
+	}
+	return val
+}
@@ -0,0 +1,121 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+// numBenchZettel is the number of zettel of the "10000" data set that are
+// used for benchmarks.
+const numBenchZettel = 2000
+
+var benchZettel = sync.OnceValues(func() ([]*zstest.Zettel, error) {
+	return zstest.LoadMarkdown("../10000/files.md", numBenchZettel)
+})
+
+// benchFeeds creates a feed set with one feed "bench" of the given limit. The
+// feeds are retrieved from a fake Zettelstore.
+func benchFeeds(b *testing.B, limit int) (*feedSet, *zstest.Server) {
+	b.Helper()
+	zs, err := benchZettel()
+	if err != nil {
+		b.Fatal(err)
+	}
+	store := zstest.NewServer(zs...)
+	zsSrv := httptest.NewServer(store)
+	b.Cleanup(zsSrv.Close)
+
+	path := filepath.Join(b.TempDir(), "feeds.txt")
+	definitions := fmt.Sprintf("feed: bench\nurl: %s\nlimit: %d\n", zsSrv.URL, limit)
+	if err = os.WriteFile(path, []byte(definitions), 0o600); err != nil {
+		b.Fatal(err)
+	}
+	feeds := newFeedSet(&fileSource{path: path}, "http://127.0.0.1:23110", time.Minute)
+	if _, err = feeds.Reload(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	return feeds, store
+}
+
+func BenchmarkRetrieve(b *testing.B) {
+	for _, limit := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			feeds, store := benchFeeds(b, limit)
+			fi, _ := feeds.Get("bench")
+			ctx := context.Background()
+			store.ResetRequests()
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := fi.retrieve(ctx, feedPage{Num: 1}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			store.ReportRequests(b)
+		})
+	}
+}
+
+func BenchmarkRenderFormats(b *testing.B) {
+	feeds, _ := benchFeeds(b, 1000)
+	fi, _ := feeds.Get("bench")
+	fd, err := fi.retrieve(context.Background(), feedPage{Num: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, format := range []string{formatRSS, formatAtom, formatJSON} {
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if err = writeFeed(io.Discard, fd, format, "http://127.0.0.1:23110/bench"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFeedRequest(b *testing.B) {
+	for _, cached := range []bool{true, false} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			feeds, store := benchFeeds(b, 100)
+			handler := makeServeMux(feeds, newHub(feeds))
+			fi, _ := feeds.Get("bench")
+			store.ResetRequests()
+			b.ReportAllocs()
+			for b.Loop() {
+				if !cached {
+					fi.invalidate()
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bench/rss.xml", nil))
+				if rec.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+				}
+			}
+			store.ReportRequests(b)
+		})
+	}
+}
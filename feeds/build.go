@@ -0,0 +1,194 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zettelstore.de/contrib/server"
+)
+
+// staticOPMLFile is the name of the OPML file of a static build.
+const staticOPMLFile = "feeds.opml"
+
+// runBuild writes all feeds, the start page, and the OPML document into a
+// directory, to be published on a static web server. It returns the exit code
+// of the program.
+func runBuild(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	outDir := fs.String("o", "", "Output directory (required)")
+	configFile := fs.String("c", "", "File with feed definitions")
+	baseURL := fs.String("u", "", "URL where the output directory will be published (required)")
+	timeout := fs.Duration("t", 30*time.Second, "Timeout for retrieving data from a Zettelstore")
+	logLevel := fs.String("log-level", "info", "Log level: debug, info, warn, error")
+	logFormat := fs.String("log-format", "text", "Log format: text, json")
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage of %s build:\n", os.Args[0])
+		fs.PrintDefaults()
+		_, _ = io.WriteString(out, "  [URL] URL of Zettelstore with feed definitions, if no file is given\n")
+	}
+	_ = fs.Parse(args)
+	if *outDir == "" {
+		fs.Usage()
+		return 2
+	}
+	base, err := makePublishBase(*baseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid URL of the output directory: %v\n", err)
+		fs.Usage()
+		return 2
+	}
+	if err = server.SetupLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to configure logging: %v\n", err)
+		return 2
+	}
+	src, err := makeFeedSource(*configFile, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to determine feed definitions: %v\n", err)
+		fs.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	b := builder{dir: *outDir, base: base}
+	feeds := newFeedSet(src, b.base, *timeout)
+	if _, err = feeds.Reload(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load feed definitions from %s: %v\n", src, err)
+		return 2
+	}
+	if err = b.build(ctx, feeds, *timeout); err != nil {
+		slog.Error("Build failed", "err", err)
+		return 1
+	}
+	slog.Info("Build completed", "dir", b.dir, "written", b.written, "unchanged", b.unchanged, "failed", b.failed)
+	if b.failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// makePublishBase returns the URL where the output directory will be
+// published, without a trailing slash. It must be absolute, because feed
+// readers need absolute URLs for the feeds within the OPML document.
+func makePublishBase(publishURL string) (string, error) {
+	u, err := url.Parse(publishURL)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute HTTP URL", publishURL)
+	}
+	return strings.TrimSuffix(publishURL, "/"), nil
+}
+
+// builder writes feeds into files.
+type builder struct {
+	dir       string
+	base      string
+	written   int
+	unchanged int
+	failed    int
+}
+
+func (b *builder) build(ctx context.Context, feeds *feedSet, timeout time.Duration) error {
+	for _, key := range feeds.Keys() {
+		if fi, found := feeds.Get(key); found {
+			fi.static = true
+		}
+	}
+	for _, key := range feeds.Keys() {
+		fi, found := feeds.Get(key)
+		if !found {
+			continue
+		}
+		for _, f := range formats {
+			rctx, cancel := context.WithTimeout(ctx, timeout)
+			rf, err := fi.render(rctx, currentPage, f.name)
+			cancel()
+			if err != nil {
+				slog.Error("Unable to retrieve feed", "feed", key, "err", err)
+				b.failed++
+				break
+			}
+			if err = b.writeFile(filepath.Join(key, f.file), rf.data); err != nil {
+				return err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	rctx, cancel := context.WithTimeout(ctx, timeout)
+	err := writeIndexPage(&buf, collectIndexFeeds(rctx, feeds, false), staticOPMLFile)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if err = b.writeFile("index.html", buf.Bytes()); err != nil {
+		return err
+	}
+
+	buf.Reset()
+	doc := makeOPMLDocument(feeds)
+	doc.Head.DateCreated = "" // Would change the file on every build
+	if err = doc.Write(&buf); err != nil {
+		return err
+	}
+	return b.writeFile(staticOPMLFile, buf.Bytes())
+}
+
+// writeFile writes the data into a file of the output directory, but only if
+// the content of the file changes. This keeps the modification time of
+// unchanged files, which helps tools like rsync.
+func (b *builder) writeFile(name string, data []byte) error {
+	path := filepath.Join(b.dir, name)
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		b.unchanged++
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	slog.Debug("File written", "path", path)
+	b.written++
+	return nil
+}
@@ -0,0 +1,230 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"t73f.de/r/zsc/client"
+)
+
+// defaultCacheTTL is used, if a feed does not specify a TTL.
+const defaultCacheTTL = time.Minute
+
+// feedCache stores the data of all retrieved pages of a feed. The data of a
+// page is retrieved by one request at a time; other requests wait for it, or
+// get the expired data, if there is any. The lock is never held while the
+// Zettelstore is accessed.
+type feedCache struct {
+	mx           sync.Mutex
+	pages        map[feedPage]*cachedPage
+	calls        map[feedPage]*cacheCall
+	gen          uint64 // Incremented on invalidation
+	count        int    // Number of zettel of a paged feed
+	countExpires time.Time
+}
+
+// cachedPage stores the data of a page of a feed, together with its rendered
+// formats.
+type cachedPage struct {
+	fd       *feedData
+	expires  time.Time
+	rendered map[string]*renderedFeed // format
+}
+
+// cacheCall is a running retrieval of the data of a page.
+type cacheCall struct {
+	done chan struct{}
+	gen  uint64
+	cp   *cachedPage
+	err  error
+}
+
+// renderedFeed is a feed in a specific format.
+type renderedFeed struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+func (fi *feedInfo) cacheTTL() time.Duration {
+	if fi.TTL > 0 {
+		return time.Duration(fi.TTL) * time.Minute
+	}
+	return defaultCacheTTL
+}
+
+// cachedData returns the data of a page of the feed, retrieving it only if
+// the cached data is expired. While the data is retrieved, expired data is
+// returned to other requests. If the Zettelstore is not available, expired
+// data is returned, if there is any.
+func (fi *feedInfo) cachedData(ctx context.Context, pg feedPage) (*cachedPage, error) {
+	fc := &fi.cache
+	fc.mx.Lock()
+	cp := fc.pages[pg]
+	if cp != nil && time.Now().Before(cp.expires) {
+		fc.mx.Unlock()
+		stats.Cache(fi.name, true)
+		return cp, nil
+	}
+	call, running := fc.calls[pg]
+	if !running {
+		call = &cacheCall{done: make(chan struct{}), gen: fc.gen}
+		if fc.calls == nil {
+			fc.calls = map[feedPage]*cacheCall{}
+		}
+		fc.calls[pg] = call
+	}
+	fc.mx.Unlock()
+
+	if running {
+		if cp != nil {
+			stats.Cache(fi.name, true)
+			return cp, nil
+		}
+		select {
+		case <-call.done:
+			return call.cp, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	fd, err := fi.retrieve(ctx, pg)
+	fc.mx.Lock()
+	current := call.gen == fc.gen
+	if fc.calls[pg] == call {
+		delete(fc.calls, pg)
+	}
+	switch {
+	case errors.Is(err, errPageNotFound):
+		if current {
+			delete(fc.pages, pg)
+		}
+		cp = nil
+	case err != nil:
+		if cp != nil {
+			slog.Warn("Serving stale feed", "feed", fi.name, "err", err)
+			stats.Cache(fi.name, true)
+			err = nil
+		}
+	default:
+		stats.Cache(fi.name, false)
+		cp = &cachedPage{fd: fd, expires: time.Now().Add(fi.cacheTTL())}
+		if current {
+			if fc.pages == nil {
+				fc.pages = map[feedPage]*cachedPage{}
+			}
+			fc.pages[pg] = cp
+		}
+	}
+	call.cp, call.err = cp, err
+	fc.mx.Unlock()
+	close(call.done)
+	return cp, err
+}
+
+// render returns a page of the feed in the given format, using cached data if
+// possible. All absolute URLs are based on the canonical URL of the feed,
+// so that there is only one rendered document per format.
+func (fi *feedInfo) render(ctx context.Context, pg feedPage, format string) (*renderedFeed, error) {
+	cp, err := fi.cachedData(ctx, pg)
+	if err != nil {
+		return nil, err
+	}
+	fc := &fi.cache
+	fc.mx.Lock()
+	rf, found := cp.rendered[format]
+	fc.mx.Unlock()
+	if found {
+		return rf, nil
+	}
+
+	fd := cp.fd
+	var buf bytes.Buffer
+	if err = writeFeed(&buf, fd, format, fi.feedURL(format)); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	rf = &renderedFeed{
+		data:     buf.Bytes(),
+		etag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+		modified: fd.LastModified(),
+	}
+	fc.mx.Lock()
+	defer fc.mx.Unlock()
+	if prev, found2 := cp.rendered[format]; found2 {
+		return prev, nil
+	}
+	if cp.rendered == nil {
+		cp.rendered = map[string]*renderedFeed{}
+	}
+	cp.rendered[format] = rf
+	return rf, nil
+}
+
+// invalidate removes all cached data of the feed. Running retrievals do not
+// store their data any more.
+func (fi *feedInfo) invalidate() {
+	fc := &fi.cache
+	fc.mx.Lock()
+	fc.pages, fc.calls = nil, nil
+	fc.countExpires = time.Time{}
+	fc.gen++
+	fc.mx.Unlock()
+}
+
+// zettelCount returns the number of zettel of a paged feed. It is cached as
+// long as the feed data, so that the zettel are not counted again for every
+// page.
+func (fi *feedInfo) zettelCount(ctx context.Context, c *client.Client, strict bool) (int, error) {
+	fc := &fi.cache
+	fc.mx.Lock()
+	if time.Now().Before(fc.countExpires) {
+		n := fc.count
+		fc.mx.Unlock()
+		return n, nil
+	}
+	gen := fc.gen
+	fc.mx.Unlock()
+
+	zl, err := c.QueryZettel(ctx, fi.selectQuery(strict))
+	if err != nil {
+		return 0, fmt.Errorf("unable to count zettel: %w", err)
+	}
+	fc.mx.Lock()
+	if gen == fc.gen {
+		fc.count, fc.countExpires = len(zl), time.Now().Add(fi.cacheTTL())
+	}
+	fc.mx.Unlock()
+	return len(zl), nil
+}
+
+// LastModified returns the time of the last change of the feed, using cached
+// data if possible.
+func (fi *feedInfo) LastModified(ctx context.Context) (time.Time, error) {
+	cp, err := fi.cachedData(ctx, currentPage)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cp.fd.LastModified(), nil
+}
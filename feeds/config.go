@@ -0,0 +1,292 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"t73f.de/r/zsc/client"
+	"t73f.de/r/zsc/webapi"
+)
+
+// Constants for keys of a feed definition. Some of them are zettel metadata
+// keys too.
+const (
+	KeyFeed           = "feed"
+	KeyTitle          = "title"
+	KeyURL            = "url"
+	KeyLink           = "link"
+	KeyDescription    = "description"
+	KeyLanguage       = "language"
+	KeyCopyright      = "copyright"
+	KeyAuthor         = "author"
+	KeyManagingEditor = "managing-editor"
+	KeyWebMaster      = "web-master"
+	KeyTTL            = "ttl"
+	KeyLimit          = "limit"
+	KeyQuery          = "query"
+	KeyUsername       = "username"
+	KeyPassword       = "password"
+)
+
+// feedSource is a place where feed definitions are stored.
+type feedSource interface {
+	Read(context.Context) ([]byte, error)
+	String() string
+}
+
+func makeFeedSource(configFile, base string) (feedSource, error) {
+	if configFile != "" {
+		return &fileSource{path: configFile}, nil
+	}
+	if base == "" {
+		return nil, errors.New("neither configuration file nor Zettelstore URL given")
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	return &zettelSource{c: client.NewClient(u)}, nil
+}
+
+// fileSource reads feed definitions from a local file.
+type fileSource struct{ path string }
+
+func (fs *fileSource) Read(context.Context) ([]byte, error) { return os.ReadFile(fs.path) }
+func (fs *fileSource) String() string                       { return fs.path }
+
+// zettelSource reads feed definitions from the content of the application
+// zettel "zettel-feeds" of a Zettelstore.
+type zettelSource struct{ c *client.Client }
+
+func (zs *zettelSource) Read(ctx context.Context) ([]byte, error) {
+	zid, err := zs.c.GetApplicationZid(ctx, "zettel-feeds")
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve configuration zettel: %w", err)
+	}
+	return zs.c.GetZettel(ctx, zid, webapi.PartContent)
+}
+func (zs *zettelSource) String() string { return zs.c.Base() }
+
+// parseFeeds parses feed definitions.
+//
+// Every line contains a key and a value, separated by a colon. A line with
+// the key "feed" starts a new definition, its value is the feed name used in
+// the URL path. Empty lines and lines starting with "#" are ignored.
+func parseFeeds(data []byte) (feeds, error) {
+	result := feeds{}
+	var fi *feedInfo
+	var name string
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, val, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: missing colon", lineNo)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		if key == KeyFeed {
+			if err := checkFeed(name, fi); err != nil {
+				return nil, err
+			}
+			if val == "" || strings.ContainsAny(val, "/?#") {
+				return nil, fmt.Errorf("line %d: invalid feed name %q", lineNo, val)
+			}
+			if _, found = result[val]; found {
+				return nil, fmt.Errorf("line %d: feed %q already defined", lineNo, val)
+			}
+			name, fi = val, &feedInfo{name: val}
+			result[name] = fi
+			continue
+		}
+		if fi == nil {
+			return nil, fmt.Errorf("line %d: key %q outside of feed definition", lineNo, key)
+		}
+		if err := fi.set(key, val); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkFeed(name, fi); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func checkFeed(name string, fi *feedInfo) error {
+	if fi == nil {
+		return nil
+	}
+	if fi.URL == "" {
+		return fmt.Errorf("feed %q: missing URL", name)
+	}
+	if _, err := url.Parse(fi.URL); err != nil {
+		return fmt.Errorf("feed %q: invalid URL: %w", name, err)
+	}
+	return nil
+}
+
+func (fi *feedInfo) set(key, val string) error {
+	switch key {
+	case KeyTitle:
+		fi.Title = val
+	case KeyURL:
+		fi.URL = val
+	case KeyLink:
+		fi.Link = val
+	case KeyDescription:
+		fi.Description = val
+	case KeyLanguage:
+		fi.Language = val
+	case KeyCopyright:
+		fi.Copyright = val
+	case KeyAuthor:
+		fi.Author = val
+	case KeyManagingEditor:
+		fi.ManagingEditor = val
+	case KeyWebMaster:
+		fi.WebMaster = val
+	case KeyTTL:
+		ttl, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("invalid TTL %q: %w", val, err)
+		}
+		fi.TTL = ttl
+	case KeyLimit:
+		limit, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("invalid limit %q: %w", val, err)
+		}
+		fi.Limit = limit
+	case KeyQuery:
+		if err := checkQuery(val); err != nil {
+			return fmt.Errorf("invalid query %q: %w", val, err)
+		}
+		fi.Query = val
+	case KeyUsername:
+		fi.Username = val
+	case KeyPassword:
+		fi.Password = val
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// feedSet contains the current feed definitions, reloaded from its source.
+type feedSet struct {
+	src     feedSource
+	reg     *upstreamRegistry
+	base    string // URL of the server, or of the published directory
+	hub     string // URL of the WebSub hub, empty if there is none
+	timeout time.Duration
+	mx      sync.RWMutex
+	feeds   feeds
+	data    []byte
+}
+
+func newFeedSet(src feedSource, base string, timeout time.Duration) *feedSet {
+	return &feedSet{src: src, reg: newUpstreamRegistry(), base: base, timeout: timeout, feeds: feeds{}}
+}
+
+// Get returns the feed definition with the given name.
+func (fs *feedSet) Get(name string) (*feedInfo, bool) {
+	fs.mx.RLock()
+	defer fs.mx.RUnlock()
+	fi, found := fs.feeds[name]
+	return fi, found
+}
+
+// Keys returns the sorted names of all feeds.
+func (fs *feedSet) Keys() []string {
+	fs.mx.RLock()
+	defer fs.mx.RUnlock()
+	return slices.Sorted(maps.Keys(fs.feeds))
+}
+
+// Reload reads the feed definitions again, if they have changed. Upstream
+// Zettelstores of changed definitions are checked before they become active.
+func (fs *feedSet) Reload(ctx context.Context) (bool, error) {
+	if fs.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fs.timeout)
+		defer cancel()
+	}
+	data, err := fs.src.Read(ctx)
+	if err != nil {
+		return false, err
+	}
+	fs.mx.RLock()
+	same := fs.data != nil && bytes.Equal(data, fs.data)
+	fs.mx.RUnlock()
+	if same {
+		return false, nil
+	}
+	newFeeds, err := parseFeeds(data)
+	if err != nil {
+		return false, err
+	}
+	for name, fi := range newFeeds {
+		fi.base, fi.path, fi.hub = fs.base, "/"+url.PathEscape(name), fs.hub
+	}
+	fs.reg.Sync(ctx, newFeeds)
+	fs.mx.Lock()
+	fs.feeds, fs.data = newFeeds, data
+	fs.mx.Unlock()
+	return true, nil
+}
+
+// Watch reloads the feed definitions periodically, until the context is done.
+// Invalid definitions are reported, but the previous ones stay active.
+func (fs *feedSet) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := fs.Reload(ctx)
+			if err != nil {
+				slog.Error("Unable to reload feed definitions", "source", fs.src, "err", err)
+			} else if changed {
+				slog.Info("Feed definitions changed", "source", fs.src, "feeds", fs.Keys())
+			}
+		}
+	}
+}
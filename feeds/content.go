@@ -0,0 +1,310 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sxwebs/sxhtml"
+	"t73f.de/r/zsc/client"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/domain/meta"
+	"t73f.de/r/zsc/shtml"
+	"t73f.de/r/zsc/sz"
+	"t73f.de/r/zsc/webapi"
+	"t73f.de/r/zsx"
+)
+
+// contentGenerator transforms the content of a zettel into HTML that can be
+// read within a feed reader. Links to zettel are made absolute, links to
+// non-public zettel are replaced by their text. If strict, linked zettel must
+// be explicitly public, as the items of the feed.
+type contentGenerator struct {
+	ctx      context.Context
+	c        *client.Client
+	tr       *shtml.Evaluator
+	lang     string
+	strict   bool
+	public   map[id.Zid]bool
+	modified map[id.Zid]string // Value of "modified" of known zettel
+	media    map[id.Zid]*feedEnclosure
+	sizes    *mediaSizes
+}
+
+func newContentGenerator(ctx context.Context, c *client.Client, lang string, strict bool, sizes *mediaSizes) *contentGenerator {
+	tr := shtml.NewEvaluator(1)
+	cg := contentGenerator{
+		ctx:      ctx,
+		c:        c,
+		tr:       tr,
+		lang:     lang,
+		strict:   strict,
+		public:   map[id.Zid]bool{},
+		modified: map[id.Zid]string{},
+		media:    map[id.Zid]*feedEnclosure{},
+		sizes:    sizes,
+	}
+
+	rebind(tr, zsx.SymLink, func(args sx.Vector, env *shtml.Environment, prevFn shtml.EvalFn) sx.Object {
+		refSym, refVal := zsx.GetReference(args[1].(*sx.Pair))
+		obj := prevFn(args, env)
+		if env.GetError() != nil {
+			return sx.Nil()
+		}
+		if !sz.SymRefStateZettel.IsEqual(refSym) {
+			return obj
+		}
+		lst, isPair := sx.GetPair(obj)
+		if !isPair {
+			return obj
+		}
+		sym, isSymbol := sx.GetSymbol(lst.Car())
+		if !isSymbol || !sym.IsEqualSymbol(shtml.SymA) {
+			return obj
+		}
+		attr, isPair := sx.GetPair(lst.Tail().Car())
+		if !isPair {
+			return obj
+		}
+		strZid, fragment, _ := strings.Cut(refVal, "#")
+		zid, err := id.Parse(strZid)
+		if err != nil || !cg.isPublic(zid) {
+			// Do not show link to other, non-public zettel
+			text := lst.Tail().Tail() // Return just the text of the link
+			return text.Cons(shtml.SymSPAN)
+		}
+		href := cg.c.NewURLBuilder('h').SetZid(zid).String()
+		if fragment != "" {
+			href += "#" + fragment
+		}
+		attr.SetCdr(attr.Tail().Cons(sx.Cons(shtml.SymAttrHref, sx.MakeString(href))))
+		return lst
+	})
+	rebind(tr, zsx.SymEmbed, func(args sx.Vector, env *shtml.Environment, prevFn shtml.EvalFn) sx.Object {
+		obj := prevFn(args, env)
+		if env.GetError() != nil {
+			return sx.Nil()
+		}
+		imgTag, isPair := sx.GetPair(obj)
+		if !isPair {
+			return obj
+		}
+		attr, isPair := sx.GetPair(imgTag.Tail().Car())
+		if !isPair {
+			return obj
+		}
+		srcAssoc := attr.Assoc(shtml.SymAttrSrc)
+		if srcAssoc == nil {
+			return obj
+		}
+		zidVal, isString := sx.GetString(srcAssoc.Cdr())
+		if !isString {
+			return obj
+		}
+		zid, err := id.Parse(zidVal.GetValue())
+		if err != nil {
+			return obj
+		}
+		if !cg.isPublic(zid) {
+			return sx.Nil()
+		}
+		srcAssoc.SetCdr(sx.MakeString(cg.c.NewURLBuilder('z').SetZid(zid).String()))
+		return obj
+	})
+	rebind(tr, zsx.SymVerbatimComment, func(sx.Vector, *shtml.Environment, shtml.EvalFn) sx.Object { return sx.Nil() })
+	rebind(tr, zsx.SymLiteralComment, func(sx.Vector, *shtml.Environment, shtml.EvalFn) sx.Object { return sx.Nil() })
+	return &cg
+}
+
+func rebind(th *shtml.Evaluator, sym *sx.Symbol, fn func(sx.Vector, *shtml.Environment, shtml.EvalFn) sx.Object) {
+	prevFn := th.ResolveBinding(sym)
+	th.Rebind(sym, func(args sx.Vector, env *shtml.Environment) sx.Object {
+		return fn(args, env, prevFn)
+	})
+}
+
+// isPublic returns true, if the zettel may be shown to everybody. Usually,
+// the zettel is already known by prefetch.
+func (cg *contentGenerator) isPublic(zid id.Zid) bool {
+	if public, found := cg.public[zid]; found {
+		return public
+	}
+	mr, err := cg.c.GetMetaData(cg.ctx, zid)
+	public := err == nil && isVisible(mr.Meta, cg.strict)
+	cg.public[zid] = public
+	if public {
+		cg.modified[zid] = mr.Meta[meta.KeyModified]
+	}
+	return public
+}
+
+// prefetch retrieves the metadata of the given zettel and of all zettel that
+// are linked or embedded within its content with a single query, to decide
+// whether they are public. It returns the embedded zettel.
+func (cg *contentGenerator) prefetch(zid id.Zid, sxContent *sx.Pair) []embedRef {
+	var rc refCollector
+	zsx.Walk(&rc, sxContent, nil)
+
+	var zids []id.Zid
+	add := func(zid id.Zid) {
+		if _, found := cg.public[zid]; !found && !slices.Contains(zids, zid) {
+			zids = append(zids, zid)
+		}
+	}
+	add(zid)
+	for _, linked := range rc.links {
+		add(linked)
+	}
+	for _, ref := range rc.embeds {
+		add(ref.zid)
+	}
+	if len(zids) == 0 {
+		return rc.embeds
+	}
+
+	zidStrings := make([]string, len(zids))
+	for i, zid := range zids {
+		zidStrings[i] = zid.String()
+	}
+	_, _, ml, err := cg.c.QueryZettelData(cg.ctx, strings.Join(zidStrings, " "))
+	if err != nil {
+		slog.Warn("Unable to retrieve metadata of linked zettel", "zid", zid, "err", err)
+		return rc.embeds
+	}
+	for _, mr := range ml {
+		cg.public[mr.ID] = isVisible(mr.Meta, cg.strict)
+		cg.modified[mr.ID] = mr.Meta[meta.KeyModified]
+	}
+	for _, zid := range zids {
+		if _, found := cg.public[zid]; !found {
+			cg.public[zid] = false // Zettel does not exist or is not readable
+		}
+	}
+	return rc.embeds
+}
+
+// Content returns the evaluated content of the given zettel as HTML,
+// together with the media files of the zettel and of all embedded zettel.
+func (cg *contentGenerator) Content(zid id.Zid) (string, []*feedEnclosure, error) {
+	sxZettel, err := cg.c.GetEvaluatedSz(cg.ctx, zid, webapi.PartZettel)
+	if err != nil {
+		return "", nil, err
+	}
+	sxMeta, sxContent := sz.GetMetaContent(sxZettel)
+	embeds := cg.prefetch(zid, sxContent)
+	enclosures := cg.enclosures(zid, sxMeta.GetString(meta.KeySyntax), embeds)
+
+	lang := sxMeta.GetString(meta.KeyLang)
+	if lang == "" {
+		lang = cg.lang
+	}
+	env := shtml.MakeEnvironment(lang)
+	htmlContent, err := cg.tr.Evaluate(sxContent, &env)
+	if err != nil {
+		return "", enclosures, err
+	}
+
+	var sb strings.Builder
+	g := sxhtml.NewGenerator()
+	for elem := range htmlContent.Values() {
+		if err = g.WriteHTML(&sb, elem); err != nil {
+			return "", enclosures, err
+		}
+	}
+	if endnotes := shtml.Endnotes(&env); endnotes != nil {
+		if err = g.WriteHTML(&sb, endnotes); err != nil {
+			return "", enclosures, err
+		}
+	}
+	return sb.String(), enclosures, nil
+}
+
+// enclosures returns the media file of a zettel, if any, followed by the
+// media files that are embedded within its content.
+func (cg *contentGenerator) enclosures(zid id.Zid, syntax string, embeds []embedRef) []*feedEnclosure {
+	var result []*feedEnclosure
+	if fe, found := cg.enclosure(zid, syntax); found {
+		result = append(result, fe)
+	}
+	for _, ref := range embeds {
+		if fe, found := cg.enclosure(ref.zid, ref.syntax); found && !slices.Contains(result, fe) {
+			result = append(result, fe)
+		}
+	}
+	return result
+}
+
+// embedRef references an embedded zettel, together with its syntax.
+type embedRef struct {
+	zid    id.Zid
+	syntax string
+}
+
+// refCollector collects all zettel that are linked or embedded within zettel
+// content.
+type refCollector struct {
+	links  []id.Zid
+	embeds []embedRef
+}
+
+func (rc *refCollector) VisitBefore(_ *sx.Pair, _ *sx.Pair) (sx.Object, bool) {
+	return nil, false
+}
+func (rc *refCollector) VisitAfter(node *sx.Pair, _ *sx.Pair) sx.Object {
+	sym, isSymbol := sx.GetSymbol(node.Car())
+	if !isSymbol {
+		return node
+	}
+	if zsx.SymLink.IsEqualSymbol(sym) {
+		refSym, refVal := zsx.GetReference(node.Tail().Tail())
+		if !sz.SymRefStateZettel.IsEqual(refSym) {
+			return node
+		}
+		strZid, _, _ := strings.Cut(refVal, "#")
+		if zid, err := id.Parse(strZid); err == nil {
+			rc.links = append(rc.links, zid)
+		}
+		return node
+	}
+	if !zsx.SymEmbed.IsEqualSymbol(sym) {
+		return node
+	}
+	argRef := node.Tail().Tail()
+	qref, isPair := sx.GetPair(argRef.Car())
+	if !isPair {
+		return node
+	}
+	symEmbedRefState, isStateSymbol := sx.GetSymbol(qref.Car())
+	if !isStateSymbol || !sz.SymRefStateZettel.IsEqualSymbol(symEmbedRefState) {
+		return node
+	}
+	zidVal, isString := sx.GetString(qref.Tail().Car())
+	if !isString {
+		return node
+	}
+	zid, err := id.Parse(zidVal.GetValue())
+	if err != nil {
+		return node
+	}
+	syntax, isString := sx.GetString(argRef.Tail().Car())
+	if !isString {
+		return node
+	}
+	rc.embeds = append(rc.embeds, embedRef{zid: zid, syntax: syntax.GetValue()})
+	return node
+}
@@ -0,0 +1,127 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/webapi"
+)
+
+// feedEnclosure is a media file that belongs to a feed item, i.e. the content
+// of a zettel with a binary syntax.
+type feedEnclosure struct {
+	URL    string
+	Type   string // MIME type
+	Length int    // in bytes
+}
+
+// Medium returns the kind of the media file, as used by Media RSS.
+func (fe *feedEnclosure) Medium() string {
+	switch medium, _, _ := strings.Cut(fe.Type, "/"); medium {
+	case "image", "audio", "video":
+		return medium
+	}
+	return "document"
+}
+
+// syntaxMIME maps the syntax of a zettel to the MIME type of its content, if
+// the content is a media file.
+var syntaxMIME = map[string]string{
+	"gif":  "image/gif",
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+	"png":  "image/png",
+	"svg":  "image/svg+xml",
+	"webp": "image/webp",
+	"pdf":  "application/pdf",
+	"mp3":  "audio/mpeg",
+	"ogg":  "audio/ogg",
+	"mp4":  "video/mp4",
+	"webm": "video/webm",
+}
+
+// maxMediaSizes is the maximum number of media files of a Zettelstore whose
+// size is cached.
+const maxMediaSizes = 4096
+
+// mediaSizes caches the size of media files across retrievals of feeds, so
+// that their content is retrieved only once. An entry is valid as long as
+// the zettel is not modified.
+type mediaSizes struct {
+	mx    sync.Mutex
+	sizes map[mediaKey]int
+}
+
+// mediaKey identifies a version of a media zettel by the value of its
+// "modified" metadata.
+type mediaKey struct {
+	zid      id.Zid
+	modified string
+}
+
+func (ms *mediaSizes) get(key mediaKey) (int, bool) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	size, found := ms.sizes[key]
+	return size, found
+}
+
+func (ms *mediaSizes) set(key mediaKey, size int) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if len(ms.sizes) >= maxMediaSizes {
+		slog.Info("Too many media sizes, clearing")
+		ms.sizes = nil
+	}
+	if ms.sizes == nil {
+		ms.sizes = map[mediaKey]int{}
+	}
+	ms.sizes[key] = size
+}
+
+// enclosure returns the media file of a zettel, if its syntax denotes one and
+// if the zettel is public. Its content is only retrieved to determine its
+// size, if the size of this version is not already known.
+func (cg *contentGenerator) enclosure(zid id.Zid, syntax string) (*feedEnclosure, bool) {
+	mimeType, found := syntaxMIME[syntax]
+	if !found || !cg.isPublic(zid) {
+		return nil, false
+	}
+	if fe, found2 := cg.media[zid]; found2 {
+		return fe, fe != nil
+	}
+	key := mediaKey{zid: zid, modified: cg.modified[zid]}
+	size, found := cg.sizes.get(key)
+	if !found {
+		data, err := cg.c.GetZettel(cg.ctx, zid, webapi.PartContent)
+		if err != nil {
+			slog.Warn("Unable to retrieve media zettel", "zid", zid, "err", err)
+			cg.media[zid] = nil
+			return nil, false
+		}
+		size = len(data)
+		cg.sizes.set(key, size)
+	}
+	fe := &feedEnclosure{
+		URL:    cg.c.NewURLBuilder('z').SetZid(zid).String(),
+		Type:   mimeType,
+		Length: size,
+	}
+	cg.media[zid] = fe
+	return fe, true
+}
@@ -0,0 +1,745 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+// numTestZettel is the number of zettel of the "10000" data set that are
+// used for testing.
+const numTestZettel = 45
+
+// loadTestZettel returns zettel of the "10000" data set. Every third zettel
+// is tagged with "#alice", every fifth zettel has the role "note", and the
+// oldest zettel is private.
+func loadTestZettel(t *testing.T) []*zstest.Zettel {
+	t.Helper()
+	zs, err := zstest.LoadMarkdown("../10000/files.md", numTestZettel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zs) != numTestZettel {
+		t.Fatalf("expected %d zettel, but got %d", numTestZettel, len(zs))
+	}
+	for i, z := range zs {
+		if i%3 == 0 {
+			z.Meta["tags"] = "#alice"
+		}
+		if i%5 == 0 {
+			z.Meta["role"] = "note"
+		}
+	}
+	zs[0].Meta["visibility"] = "private"
+	return append(zs,
+		zstest.NewZettel(zstest.ZidConfiguration, "",
+			"title", "Zettelstore Runtime Configuration",
+			"role", "configuration",
+			"site-name", "Ten Thousand",
+			"lang", "en",
+			"default-license", "CC BY 4.0",
+		),
+		zstest.NewZettel(zstest.ZidDefaultHome, "Welcome to [[the first zettel|19800101000100]].",
+			"title", "Home",
+			"role", "zettel",
+			"summary", "Zettel for testing",
+			"created", "20200101000000",
+		),
+	)
+}
+
+// startFeeds starts a feeds server for the given feed definitions. The
+// string "{URL}" within the definitions is replaced by the URL of the
+// Zettelstore.
+func startFeeds(t *testing.T, store *zstest.Server, definitions string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	feeds := loadFeeds(t, store, "http://"+srv.Listener.Addr().String(), definitions)
+	srv.Config.Handler = makeServeMux(feeds, newHub(feeds))
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// loadFeeds returns the feed set for the given feed definitions, as served
+// at the given base URL.
+func loadFeeds(t *testing.T, store *zstest.Server, base, definitions string) *feedSet {
+	t.Helper()
+	zsSrv := httptest.NewServer(store)
+	t.Cleanup(zsSrv.Close)
+
+	path := filepath.Join(t.TempDir(), "feeds.txt")
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(definitions, "{URL}", zsSrv.URL)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	feeds := newFeedSet(&fileSource{path: path}, base, 5*time.Second)
+	feeds.hub = base + hubPath
+	if _, err := feeds.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return feeds
+}
+
+const testDefinitions = `feed: all
+url: {URL}
+limit: 10
+ttl: 5
+
+feed: alice
+url: {URL}
+title: Alice
+query: tags:#alice
+`
+
+func getPage(t *testing.T, srv *httptest.Server, path string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestFeedFormats(t *testing.T) {
+	zs := loadTestZettel(t)
+	srv := startFeeds(t, zstest.NewServer(zs...), testDefinitions)
+
+	testcases := []struct {
+		path        string
+		contentType string
+		item        string
+	}{
+		{"/all/rss.xml", "application/rss+xml", "<item>"},
+		{"/all/atom.xml", "application/atom+xml", "<entry>"},
+		{"/all/feed.json", "application/feed+json", `"id":`},
+	}
+	for _, tc := range testcases {
+		resp, body := getPage(t, srv, tc.path)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected status 200, but got %d", tc.path, resp.StatusCode)
+			continue
+		}
+		if got := resp.Header.Get("Content-Type"); got != tc.contentType {
+			t.Errorf("%s: expected content type %q, but got %q", tc.path, tc.contentType, got)
+		}
+		if got := strings.Count(body, tc.item); got != 10 {
+			t.Errorf("%s: expected 10 items, but got %d", tc.path, got)
+		}
+		for _, s := range []string{"Ten Thousand", "Home", zs[numTestZettel-1].Meta["title"]} {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: %q not found", tc.path, s)
+			}
+		}
+		if strings.Contains(body, zs[numTestZettel-10].Meta["title"]) {
+			t.Errorf("%s: zettel %q should be on the next page", tc.path, zs[numTestZettel-10].Meta["title"])
+		}
+		if link := resp.Header.Get("Link"); !strings.Contains(link, `rel="hub"`) || !strings.Contains(link, srv.URL+tc.path) {
+			t.Errorf("%s: unexpected Link header %q", tc.path, link)
+		}
+	}
+
+	resp, _ := getPage(t, srv, "/all/", "Accept", "application/atom+xml")
+	if got := resp.Header.Get("Content-Type"); got != "application/atom+xml" {
+		t.Errorf("content negotiation failed, got %q", got)
+	}
+	if resp, _ = getPage(t, srv, "/missing/rss.xml"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown feed, but got %d", resp.StatusCode)
+	}
+}
+
+func TestCanonicalFeedURL(t *testing.T) {
+	srv := startFeeds(t, zstest.NewServer(loadTestZettel(t)...), testDefinitions)
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/all/atom.xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "attacker.example"
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	if strings.Contains(body, "attacker.example") || strings.Contains(resp.Header.Get("Link"), "attacker.example") {
+		t.Errorf("host of client is used in feed: %s", body)
+	}
+	if !strings.Contains(body, srv.URL+"/all/atom.xml") {
+		t.Errorf("canonical feed URL not found: %s", body)
+	}
+}
+
+func TestFeedCacheConcurrent(t *testing.T) {
+	store := zstest.NewServer(loadTestZettel(t)...)
+	srv := startFeeds(t, store, testDefinitions)
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Go(func() {
+			if resp, _ := getPage(t, srv, "/all/"+formats[i%len(formats)].file); resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status 200, but got %d", resp.StatusCode)
+			}
+		})
+	}
+	wg.Wait()
+	if got := store.Requests()["sz"]; got != 10 {
+		t.Errorf("expected content of 10 zettel to be retrieved once, but got %d requests", got)
+	}
+}
+
+func TestMediaSizes(t *testing.T) {
+	store := zstest.NewServer(
+		zstest.NewZettel("20250101000000", "PNGDATA", "title", "Image", "syntax", "png", "visibility", "public"),
+		zstest.NewZettel("20250102000000", "An image: {{20250101000000}}", "title", "Post", "tags", "#media", "visibility", "public"),
+	)
+	feeds := loadFeeds(t, store, "http://127.0.0.1:23110", "feed: media\nurl: {URL}\nquery: tags:#media\n")
+	ctx := context.Background()
+	fi, _ := feeds.Get("media")
+	for range 2 {
+		fd, err := fi.retrieve(ctx, currentPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fd.Items) != 1 || len(fd.Items[0].Enclosures) != 1 || fd.Items[0].Enclosures[0].Length != 7 {
+			t.Fatalf("expected one enclosure of 7 bytes, but got %v", fd.Items)
+		}
+	}
+	if got := store.Requests()["content"]; got != 1 {
+		t.Errorf("expected media zettel to be retrieved once, but got %d requests", got)
+	}
+}
+
+func TestMakeServerBase(t *testing.T) {
+	testcases := []struct {
+		url    string
+		listen string
+		exp    string
+	}{
+		{"", ":23110", "http://localhost:23110"},
+		{"", "127.0.0.1:8080", "http://127.0.0.1:8080"},
+		{"https://feeds.example/", ":23110", "https://feeds.example"},
+		{"https://feeds.example/sub", ":23110", ""},
+		{"feeds.example", ":23110", ""},
+		{"ftp://feeds.example", ":23110", ""},
+	}
+	for _, tc := range testcases {
+		got, err := makeServerBase(tc.url, tc.listen)
+		if got != tc.exp || (err == nil) != (tc.exp != "") {
+			t.Errorf("makeServerBase(%q, %q) == %q, but got %q (%v)", tc.url, tc.listen, tc.exp, got, err)
+		}
+	}
+}
+
+func TestMakePublishBase(t *testing.T) {
+	testcases := []struct {
+		url string
+		exp string
+	}{
+		{"", ""},
+		{"/feeds", ""},
+		{"feeds.example/feeds", ""},
+		{"https://feeds.example/feeds/", "https://feeds.example/feeds"},
+		{"http://feeds.example", "http://feeds.example"},
+	}
+	for _, tc := range testcases {
+		got, err := makePublishBase(tc.url)
+		if got != tc.exp || (err == nil) != (tc.exp != "") {
+			t.Errorf("makePublishBase(%q) == %q, but got %q (%v)", tc.url, tc.exp, got, err)
+		}
+	}
+}
+
+func TestFeedContent(t *testing.T) {
+	store := zstest.NewServer(loadTestZettel(t)...)
+	srv := startFeeds(t, store, testDefinitions)
+	resp, body := getPage(t, srv, "/all/feed.json")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", resp.StatusCode)
+	}
+	var jf jsonFeed
+	if err := json.Unmarshal([]byte(body), &jf); err != nil {
+		t.Fatal(err)
+	}
+	if jf.Title != "Ten Thousand" || jf.Description != "Zettel for testing" {
+		t.Errorf("unexpected title %q or description %q", jf.Title, jf.Description)
+	}
+	if len(jf.Items) == 0 || jf.Items[0].Title != "Home" {
+		t.Fatalf("home zettel is not the newest item: %v", jf.Items)
+	}
+	if jf.NextURL == "" {
+		t.Error("missing next_url")
+	}
+	for _, item := range jf.Items {
+		if item.ContentHTML == "" {
+			t.Errorf("item %s has no content", item.ID)
+		}
+	}
+	if got := jf.Items[0].ContentHTML; !strings.Contains(got, "the first zettel") {
+		t.Errorf("link text of home zettel not found in %q", got)
+	}
+
+	// Metadata of linked zettel is retrieved with one query per item, only
+	// the configuration and the home zettel are retrieved one by one.
+	if got := store.Requests()["meta"]; got > 2 {
+		t.Errorf("expected at most 2 metadata requests, but got %d", got)
+	}
+}
+
+func TestFeedConditionalGet(t *testing.T) {
+	srv := startFeeds(t, zstest.NewServer(loadTestZettel(t)...), testDefinitions)
+	resp, _ := getPage(t, srv, "/all/rss.xml")
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("missing Last-Modified")
+	}
+	if resp, _ = getPage(t, srv, "/all/rss.xml", "If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match: expected status 304, but got %d", resp.StatusCode)
+	}
+	if resp, _ = getPage(t, srv, "/all/rss.xml", "If-Modified-Since", lastModified); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: expected status 304, but got %d", resp.StatusCode)
+	}
+	before := "Mon, 01 Jan 2001 00:00:00 GMT"
+	if resp, _ = getPage(t, srv, "/all/rss.xml", "If-Modified-Since", before); resp.StatusCode != http.StatusOK {
+		t.Errorf("If-Modified-Since %s: expected status 200, but got %d", before, resp.StatusCode)
+	}
+}
+
+func TestFeedPaging(t *testing.T) {
+	zs := loadTestZettel(t)
+	srv := startFeeds(t, zstest.NewServer(zs...), testDefinitions)
+
+	// 45 zettel of the data set and the home zettel are counted, but the
+	// oldest zettel is private and not shown.
+	testcases := []struct {
+		query  string
+		status int
+		items  int
+	}{
+		{"?page=2", http.StatusOK, 10},
+		{"?page=5", http.StatusOK, 5},
+		{"?page=6", http.StatusNotFound, 0},
+		{"?page=x", http.StatusBadRequest, 0},
+		{"?archive=1", http.StatusOK, 9},
+		{"?archive=4", http.StatusOK, 10},
+		{"?archive=5", http.StatusNotFound, 0},
+	}
+	for _, tc := range testcases {
+		resp, body := getPage(t, srv, "/all/atom.xml"+tc.query)
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d, but got %d", tc.query, tc.status, resp.StatusCode)
+			continue
+		}
+		if got := strings.Count(body, "<entry>"); tc.status == http.StatusOK && got != tc.items {
+			t.Errorf("%s: expected %d items, but got %d", tc.query, tc.items, got)
+		}
+	}
+
+	_, body := getPage(t, srv, "/all/atom.xml?archive=1")
+	if !strings.Contains(body, zs[1].Meta["title"]) || strings.Contains(body, zs[0].Meta["title"]) {
+		t.Error("first archive should contain the oldest public zettel")
+	}
+}
+
+func TestFeedPagingCount(t *testing.T) {
+	store := zstest.NewServer(loadTestZettel(t)...)
+	feeds := loadFeeds(t, store, "https://feeds.example", testDefinitions)
+	fi, _ := feeds.Get("all")
+	ctx := context.Background()
+	c, withAuth, err := fi.up.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.ResetRequests()
+	for range 3 {
+		if n, errCount := fi.zettelCount(ctx, c, withAuth); errCount != nil || n < 40 || n >= 50 {
+			t.Fatalf("expected 5 pages of zettel, but got %d / %v", n, errCount)
+		}
+	}
+	if got := store.Requests()["query"]; got != 1 {
+		t.Errorf("expected zettel to be counted once, but got %d queries", got)
+	}
+	fi.invalidate()
+	if _, errCount := fi.zettelCount(ctx, c, withAuth); errCount != nil {
+		t.Fatal(errCount)
+	}
+	if got := store.Requests()["query"]; got != 2 {
+		t.Errorf("expected zettel to be counted again after invalidation, but got %d queries", got)
+	}
+}
+
+func TestFilteredFeeds(t *testing.T) {
+	zs := loadTestZettel(t)
+	srv := startFeeds(t, zstest.NewServer(zs...), testDefinitions)
+
+	resp, body := getPage(t, srv, "/all/tag/alice/rss.xml")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", resp.StatusCode)
+	}
+	if got := strings.Count(body, "<item>"); got != 10 {
+		t.Errorf("expected 10 items, but got %d", got)
+	}
+	if !strings.Contains(body, "Ten Thousand – #alice") {
+		t.Error("title of filtered feed not found")
+	}
+	if !strings.Contains(body, zs[42].Meta["title"]) || strings.Contains(body, zs[44].Meta["title"]) {
+		t.Error("tag feed contains wrong zettel")
+	}
+
+	// Zettel 5, 10, ..., 40 have role "note", zettel 0 is private.
+	if _, body = getPage(t, srv, "/all/role/note/rss.xml"); strings.Count(body, "<item>") != 8 {
+		t.Errorf("expected 8 items with role note, but got %d", strings.Count(body, "<item>"))
+	}
+	if _, body = getPage(t, srv, "/alice/rss.xml"); strings.Count(body, "<item>") != 14 {
+		t.Errorf("expected 14 items tagged alice, but got %d", strings.Count(body, "<item>"))
+	}
+	if resp, _ = getPage(t, srv, "/all/tag/a%20b/rss.xml"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for invalid tag, but got %d", resp.StatusCode)
+	}
+	if resp, _ = getPage(t, srv, "/all/tag/nonexistent/rss.xml"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown tag, but got %d", resp.StatusCode)
+	}
+	if resp, _ = getPage(t, srv, "/alice/role/note/rss.xml"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for role of tagged zettel, but got %d", resp.StatusCode)
+	}
+}
+
+func TestTopicsConcurrent(t *testing.T) {
+	store := zstest.NewServer(loadTestZettel(t)...)
+	feeds := loadFeeds(t, store, "https://feeds.example", testDefinitions)
+	fi, _ := feeds.Get("all")
+	store.ResetRequests()
+	store.SetLatency(10 * time.Millisecond)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if ft, err := fi.Topics(context.Background()); err != nil || len(ft.Tags) == 0 {
+				t.Errorf("expected topics, but got %v / %v", ft, err)
+			}
+		})
+	}
+	wg.Wait()
+	// Visibility, tags, and roles are aggregated once.
+	if got := store.Requests()["query"]; got != 3 {
+		t.Errorf("expected 3 queries, but got %d", got)
+	}
+}
+
+func TestStartPageAndOPML(t *testing.T) {
+	srv := startFeeds(t, zstest.NewServer(loadTestZettel(t)...), testDefinitions)
+
+	resp, body := getPage(t, srv, "/")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", resp.StatusCode)
+	}
+	for _, s := range []string{"Ten Thousand", "Alice", `href="./all/rss.xml"`, `href="./all/tag/alice/"`, `href="opml"`} {
+		if !strings.Contains(body, s) {
+			t.Errorf("start page does not contain %q", s)
+		}
+	}
+
+	resp, body = getPage(t, srv, "/opml")
+	if got := resp.Header.Get("Content-Type"); got != opmlContentType {
+		t.Errorf("expected content type %q, but got %q", opmlContentType, got)
+	}
+	if !strings.Contains(body, `xmlUrl="`+srv.URL+`/alice/rss.xml"`) {
+		t.Errorf("OPML does not contain feed URL: %s", body)
+	}
+}
+
+func TestStartPageConcurrent(t *testing.T) {
+	zs := loadTestZettel(t)
+	collect := func(n int) time.Duration {
+		var sb strings.Builder
+		for i := range n {
+			fmt.Fprintf(&sb, "feed: f%d\nurl: {URL}\nquery: tags:#alice\n\n", i)
+		}
+		store := zstest.NewServer(zs...)
+		feeds := loadFeeds(t, store, "https://feeds.example", sb.String())
+		store.SetLatency(20 * time.Millisecond)
+		start := time.Now()
+		if infs := collectIndexFeeds(context.Background(), feeds, true); len(infs) != n {
+			t.Fatalf("expected %d feeds, but got %d", n, len(infs))
+		}
+		return time.Since(start)
+	}
+
+	// With 4 concurrent retrievals, 8 feeds should take about twice the
+	// time of one feed, but not eight times.
+	one, eight := collect(1), collect(8)
+	if eight >= 4*one {
+		t.Errorf("feeds are not retrieved concurrently: one feed %v, eight feeds %v", one, eight)
+	}
+}
+
+func TestAuthenticatedFeed(t *testing.T) {
+	zs := loadTestZettel(t)
+	for i := 4; i < numTestZettel; i += 10 {
+		zs[i].Meta["visibility"] = "public"
+	}
+	store := zstest.NewServer(zs...)
+	store.AddUser("reader", "secret")
+	srv := startFeeds(t, store, "feed: all\nurl: {URL}\nusername: reader\npassword: secret\n")
+
+	_, body := getPage(t, srv, "/all/rss.xml")
+	if got := strings.Count(body, "<item>"); got != 5 {
+		t.Errorf("expected 5 public items, but got %d", got)
+	}
+	if store.Requests()["auth"] == 0 {
+		t.Error("feeds did not authenticate")
+	}
+}
+
+func TestUpstreamClient(t *testing.T) {
+	store := zstest.NewServer(loadTestZettel(t)...)
+	store.AddUser("reader", "secret")
+	zsSrv := httptest.NewServer(store)
+	defer zsSrv.Close()
+	base, err := url.Parse(zsSrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	up := newUpstream(base, "reader", "secret")
+	c1, withAuth, err := up.Client(ctx)
+	if err != nil || !withAuth {
+		t.Fatalf("expected authenticated client, but got %v / %v", withAuth, err)
+	}
+
+	// A stale upstream is checked once, other requests use the valid client.
+	up.mx.Lock()
+	up.checked = time.Time{}
+	up.mx.Unlock()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, _, errClient := up.Client(ctx); errClient != nil {
+				t.Error(errClient)
+			}
+		})
+	}
+	wg.Wait()
+	c2, _, err := up.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Error("client was not replaced by the check")
+	}
+	if got := store.Requests()["auth"]; got != 2 {
+		t.Errorf("expected 2 authentications, but got %d", got)
+	}
+
+	// A request that is canceled during a check does not make the upstream fail.
+	up.mx.Lock()
+	up.checked = time.Time{}
+	up.mx.Unlock()
+	store.SetLatency(50 * time.Millisecond)
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, _ = up.Client(reqCtx)
+	store.SetLatency(0)
+	if _, stale, errCheck := up.current(); errCheck != nil || stale {
+		t.Errorf("check should succeed despite canceled request, but got stale=%v / %v", stale, errCheck)
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	testcases := []struct {
+		query string
+		ok    bool
+	}{
+		{"", true},
+		{"tags:#alice", true},
+		{"visibility:public", true},
+		{"visibility=public tags:#alice", true},
+		{"visibility:login", false},
+		{"visibility!=public", false},
+		{"visibility!:public", false},
+		{"Visibility~pub", false},
+		{"visibility?", false},
+		{"tags:#alice | tags", false},
+	}
+	for _, tc := range testcases {
+		if err := checkQuery(tc.query); (err == nil) != tc.ok {
+			t.Errorf("checkQuery(%q): unexpected result %v", tc.query, err)
+		}
+	}
+}
+
+func TestRestrictQuery(t *testing.T) {
+	const term = "visibility=public"
+	testcases := []struct {
+		query string
+		exp   string
+	}{
+		{"role!=configuration", "role!=configuration " + term},
+		{"tags:#a OR tags:#b", "tags:#a " + term + " OR tags:#b " + term},
+		{"tags:#a OR tags:#b ORDER title LIMIT 3", "tags:#a " + term + " OR tags:#b " + term + " ORDER title LIMIT 3"},
+		{"PICK 5", term + " PICK 5"},
+	}
+	for _, tc := range testcases {
+		if got := restrictQuery(tc.query, term); got != tc.exp {
+			t.Errorf("restrictQuery(%q) == %q, but got %q", tc.query, tc.exp, got)
+		}
+	}
+
+	if got, exp := stripDirectives("tags:#a OR tags:#b ORDER title LIMIT 3"), "tags:#a OR tags:#b"; got != exp {
+		t.Errorf("stripDirectives should return %q, but got %q", exp, got)
+	}
+
+	fi := &feedInfo{Query: "role:note OR tags:#bob ORDER title"}
+	if got, exp := fi.derive("x", "#alice", "tags:#alice").Query, "role:note tags:#alice OR tags:#bob tags:#alice ORDER title"; got != exp {
+		t.Errorf("derived query should be %q, but got %q", exp, got)
+	}
+}
+
+func TestAtomFeedID(t *testing.T) {
+	srv := startFeeds(t, zstest.NewServer(loadTestZettel(t)...), testDefinitions)
+	for _, path := range []string{"/all/atom.xml", "/alice/atom.xml", "/all/tag/alice/atom.xml"} {
+		if _, body := getPage(t, srv, path); !strings.Contains(body, "<id>"+srv.URL+path+"</id>") {
+			t.Errorf("%s: feed identifier not found in %s", path, body)
+		}
+	}
+	if got, exp := atomFeedID("all/tag/alice", ""), "urn:zettel-feeds:all%2Ftag%2Falice"; got != exp {
+		t.Errorf("expected identifier %q, but got %q", exp, got)
+	}
+}
+
+func TestHubRequests(t *testing.T) {
+	ctx := context.Background()
+	feeds := loadFeeds(t, zstest.NewServer(loadTestZettel(t)...), "https://feeds.example", testDefinitions)
+	topics := []struct {
+		topic string
+		found bool
+	}{
+		{"https://feeds.example/all/rss.xml", true},
+		{"https://FEEDS.example/all/", true},
+		{"https://feeds.example/all/tag/alice/atom.xml", true},
+		{"https://feeds.example/all/tag/nonexistent/atom.xml", false},
+		{"https://other.example/all/rss.xml", false},
+		{"http://feeds.example/all/rss.xml", false},
+	}
+	for _, tc := range topics {
+		if _, _, found := resolveTopic(ctx, feeds, tc.topic); found != tc.found {
+			t.Errorf("resolveTopic(%q) should be %v", tc.topic, tc.found)
+		}
+	}
+
+	callbacks := []struct {
+		callback string
+		valid    bool
+	}{
+		{"https://subscriber.example/cb", true},
+		{"http://203.0.113.7:8080/cb", true},
+		{"http://127.0.0.1:8080/cb", false},
+		{"http://localhost/cb", false},
+		{"http://[::1]/cb", false},
+		{"http://10.1.2.3/cb", false},
+		{"http://169.254.169.254/latest", false},
+		{"http://[::ffff:192.168.0.1]/cb", false},
+		{"http://0.0.0.0/cb", false},
+		{"ftp://subscriber.example/cb", false},
+	}
+	for _, tc := range callbacks {
+		if _, valid := checkCallback(tc.callback); valid != tc.valid {
+			t.Errorf("checkCallback(%q) should be %v", tc.callback, tc.valid)
+		}
+	}
+
+	h := newHub(feeds)
+	for range maxPendingPerHost {
+		if !h.startVerification("subscriber.example") {
+			t.Fatal("verification should be allowed")
+		}
+	}
+	if h.startVerification("subscriber.example") {
+		t.Error("too many verifications for a host")
+	}
+	if h.endVerification("subscriber.example"); !h.startVerification("subscriber.example") {
+		t.Error("verification should be allowed after another one ended")
+	}
+	h.Watch(ctx, 0) // Must return immediately
+}
+
+func TestHubIsChanged(t *testing.T) {
+	ctx := context.Background()
+	zs := loadTestZettel(t)
+	store := zstest.NewServer(zs...)
+	feeds := loadFeeds(t, store, "https://feeds.example", "feed: titles\nurl: {URL}\nquery: role!=configuration ORDER title LIMIT 5\n")
+	fi, _ := feeds.Get("titles")
+	h := newHub(feeds)
+	for range 2 {
+		if changed, err := h.isChanged(ctx, fi); err != nil || changed {
+			t.Fatalf("unchanged feed should not be reported, but got %v / %v", changed, err)
+		}
+	}
+	zs[20].Meta["modified"] = "20990101000000"
+	if changed, err := h.isChanged(ctx, fi); err != nil || !changed {
+		t.Errorf("modified zettel should be reported, but got %v / %v", changed, err)
+	}
+}
+
+func TestHubDisabled(t *testing.T) {
+	feeds := loadFeeds(t, zstest.NewServer(loadTestZettel(t)...), "https://feeds.example", testDefinitions)
+	fi, _ := feeds.Get("all")
+	fi.hub = ""
+	rf, err := fi.render(context.Background(), currentPage, formatAtom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rf.data), `rel="hub"`) {
+		t.Error("feed should not announce a hub")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, hubPath, strings.NewReader("hub.mode=subscribe"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	makeServeMux(feeds, nil).ServeHTTP(rec, req)
+	if rec.Code/100 == 2 {
+		t.Errorf("subscription should be refused, but got %d", rec.Code)
+	}
+}
@@ -0,0 +1,283 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"t73f.de/r/zsc/client"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/domain/meta"
+	"t73f.de/r/zsc/webapi"
+)
+
+// Kinds of filtered feeds, used as part of the URL path.
+const (
+	filterTag  = "tag"
+	filterRole = "role"
+)
+
+// maxFilters is the maximum number of filtered feeds that are kept for a
+// feed. Since filtered feeds are created on request, this protects against
+// requests for many non-existing tags or roles.
+const maxFilters = 256
+
+// errInvalidFilter is returned, if a filtered feed cannot be created, e.g.
+// because no zettel of the feed has the tag or role.
+var errInvalidFilter = errors.New("invalid filter")
+
+// feedFilters stores the filtered feeds of a feed, together with the tags
+// and roles of its zettel. Like the feed cache, the topics are retrieved by
+// one request at a time, and the lock is never held while the Zettelstore is
+// accessed.
+type feedFilters struct {
+	mx      sync.Mutex
+	feeds   map[string]*feedInfo
+	topics  *feedTopics
+	expires time.Time
+	call    *topicsCall // Running retrieval of the topics, if any
+}
+
+// topicsCall is a running retrieval of the topics of a feed.
+type topicsCall struct {
+	done chan struct{}
+	ft   *feedTopics
+	err  error
+}
+
+// Filtered returns the feed that contains only the zettel with the given
+// tag or role. The tag or role must be one of the topics of the feed.
+func (fi *feedInfo) Filtered(ctx context.Context, kind, value string) (*feedInfo, error) {
+	value = strings.TrimPrefix(value, "#")
+	if value == "" || strings.IndexFunc(value, unicode.IsSpace) >= 0 || strings.Contains(value, "|") {
+		return nil, errInvalidFilter
+	}
+	ft, err := fi.Topics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var term, filter string
+	switch kind {
+	case filterTag:
+		if value, err = findTopic(ft.Tags, value); err != nil {
+			return nil, err
+		}
+		term = meta.KeyTags + webapi.SearchOperatorHas + "#" + value
+		filter = "#" + value
+	case filterRole:
+		if value, err = findTopic(ft.Roles, value); err != nil {
+			return nil, err
+		}
+		term = meta.KeyRole + webapi.SearchOperatorHas + value
+		filter = value
+	default:
+		return nil, errInvalidFilter
+	}
+
+	ff := &fi.filters
+	ff.mx.Lock()
+	defer ff.mx.Unlock()
+	key := kind + "/" + value
+	if result, found := ff.feeds[key]; found {
+		return result, nil
+	}
+	if len(ff.feeds) >= maxFilters {
+		slog.Info("Too many filtered feeds, clearing", "feed", fi.name)
+		ff.feeds = nil
+	}
+	if ff.feeds == nil {
+		ff.feeds = map[string]*feedInfo{}
+	}
+	result := fi.derive(fi.name+"/"+key, filter, term)
+	result.path = fi.path + "/" + kind + "/" + url.PathEscape(value)
+	ff.feeds[key] = result
+	return result, nil
+}
+
+// findTopic returns the name of the topic that matches the given value,
+// ignoring the case of letters.
+func findTopic(topics []feedTopic, value string) (string, error) {
+	for _, t := range topics {
+		if strings.EqualFold(t.Name, value) {
+			return t.Name, nil
+		}
+	}
+	return "", errInvalidFilter
+}
+
+// derive returns a new feed with the settings of the given feed, where every
+// alternative of the query is restricted by an additional search term.
+func (fi *feedInfo) derive(name, filter, term string) *feedInfo {
+	query := strings.TrimSpace(fi.Query)
+	if query == "" {
+		query = defaultSelect
+	}
+	return &feedInfo{
+		name:           name,
+		Title:          fi.Title,
+		URL:            fi.URL,
+		Link:           fi.Link,
+		Description:    fi.Description,
+		Language:       fi.Language,
+		Copyright:      fi.Copyright,
+		Author:         fi.Author,
+		ManagingEditor: fi.ManagingEditor,
+		WebMaster:      fi.WebMaster,
+		TTL:            fi.TTL,
+		Limit:          fi.Limit,
+		Query:          restrictQuery(query, term),
+		Username:       fi.Username,
+		Password:       fi.Password,
+		base:           fi.base,
+		hub:            fi.hub,
+		filter:         filter,
+		up:             fi.up,
+	}
+}
+
+// feedTopics contains the tags and roles of the zettel of a feed.
+type feedTopics struct {
+	Tags  []feedTopic
+	Roles []feedTopic
+}
+
+// feedTopic is a tag or a role, together with the number of zettel.
+type feedTopic struct {
+	Name  string
+	Count int
+}
+
+// Topics returns the tags and the roles of all zettel of the feed. They are
+// cached as long as the feed data. While they are retrieved, expired topics
+// are returned to other requests. If the Zettelstore is not available,
+// expired topics are returned, if there are any.
+func (fi *feedInfo) Topics(ctx context.Context) (*feedTopics, error) {
+	ff := &fi.filters
+	ff.mx.Lock()
+	ft := ff.topics
+	if ft != nil && time.Now().Before(ff.expires) {
+		ff.mx.Unlock()
+		return ft, nil
+	}
+	call, running := ff.call, ff.call != nil
+	if !running {
+		call = &topicsCall{done: make(chan struct{})}
+		ff.call = call
+	}
+	ff.mx.Unlock()
+
+	if running {
+		if ft != nil {
+			return ft, nil
+		}
+		select {
+		case <-call.done:
+			return call.ft, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	newTopics, err := fi.retrieveTopics(ctx)
+	ff.mx.Lock()
+	ff.call = nil
+	switch {
+	case err == nil:
+		ft, ff.topics, ff.expires = newTopics, newTopics, time.Now().Add(fi.cacheTTL())
+	case ft != nil:
+		slog.Warn("Serving stale topics", "feed", fi.name, "err", err)
+		err = nil
+	}
+	call.ft, call.err = ft, err
+	ff.mx.Unlock()
+	close(call.done)
+	return ft, err
+}
+
+// retrieveTopics aggregates the tags and roles within the Zettelstore, so
+// that only the zettel identifier are transferred, not their metadata.
+func (fi *feedInfo) retrieveTopics(ctx context.Context) (*feedTopics, error) {
+	c, withAuth, err := fi.up.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer func() { stats.Upstream(fi.up.base.String(), time.Since(start)) }()
+
+	query := fi.selectQuery(withAuth)
+	var hidden map[id.Zid]bool
+	if !withAuth {
+		// The query does not restrict the visibility, see isVisible.
+		agg, errAgg := c.QueryAggregate(ctx, query+" | "+meta.KeyVisibility)
+		if errAgg != nil {
+			return nil, fmt.Errorf("unable to aggregate %s: %w", meta.KeyVisibility, errAgg)
+		}
+		hidden = map[id.Zid]bool{}
+		for vis, zids := range agg {
+			if vis != meta.ValueVisibilityPublic {
+				for _, zid := range zids {
+					hidden[zid] = true
+				}
+			}
+		}
+	}
+	tags, err := aggregateTopics(ctx, c, query, meta.KeyTags, hidden)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := aggregateTopics(ctx, c, query, meta.KeyRole, hidden)
+	if err != nil {
+		return nil, err
+	}
+	return &feedTopics{Tags: tags, Roles: roles}, nil
+}
+
+func aggregateTopics(ctx context.Context, c *client.Client, query, key string, hidden map[id.Zid]bool) ([]feedTopic, error) {
+	agg, err := c.QueryAggregate(ctx, query+" | "+key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to aggregate %s: %w", key, err)
+	}
+	counts := make(map[string]int, len(agg))
+	for name, zids := range agg {
+		if name = strings.TrimPrefix(name, "#"); name == "" {
+			continue
+		}
+		for _, zid := range zids {
+			if !hidden[zid] {
+				counts[name]++
+			}
+		}
+	}
+	return sortedTopics(counts), nil
+}
+
+func sortedTopics(counts map[string]int) []feedTopic {
+	result := make([]feedTopic, 0, len(counts))
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		result = append(result, feedTopic{Name: name, Count: counts[name]})
+	}
+	slices.SortStableFunc(result, func(a, b feedTopic) int { return cmp.Compare(b.Count, a.Count) })
+	return result
+}
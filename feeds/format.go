@@ -0,0 +1,135 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Constants for supported feed formats.
+const (
+	formatRSS  = "rss"
+	formatAtom = "atom"
+	formatJSON = "json"
+)
+
+// formats lists all supported feed formats, the default format comes first.
+var formats = []struct {
+	name        string
+	contentType string
+	file        string
+	title       string
+}{
+	{formatRSS, "application/rss+xml", "rss.xml", "RSS"},
+	{formatAtom, "application/atom+xml", "atom.xml", "Atom"},
+	{formatJSON, "application/feed+json", "feed.json", "JSON Feed"},
+}
+
+func contentType(format string) string {
+	for _, f := range formats {
+		if f.name == format {
+			return f.contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// negotiateFormat selects the feed format that is best accepted by a client,
+// according to the value of the "Accept" header.
+func negotiateFormat(accept string) string {
+	result, bestQ := formats[0].name, 0.0
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			if key, val, found := strings.Cut(strings.TrimSpace(param), "="); found && key == "q" {
+				if fval, err := strconv.ParseFloat(val, 64); err == nil {
+					q = fval
+				}
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		for _, f := range formats {
+			if f.contentType == mediaType {
+				result, bestQ = f.name, q
+				break
+			}
+		}
+	}
+	return result
+}
+
+// makeServerBase returns the URL of the server, as used by clients, without
+// a trailing slash. It is used for all absolute URLs of the feeds, instead of
+// the host given by a client. If no URL is given, it is derived from the
+// listen address.
+func makeServerBase(serverURL, listenAddress string) (string, error) {
+	if serverURL == "" {
+		host, port, err := net.SplitHostPort(listenAddress)
+		if err != nil {
+			return "", err
+		}
+		if host == "" {
+			host = "localhost"
+		}
+		return "http://" + net.JoinHostPort(host, port), nil
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute HTTP URL", serverURL)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%q must not contain a path, a query, a fragment, or user data", serverURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// feedURL returns the canonical URL of the feed in the given format, without
+// any page selection. It is empty, if no base URL is known.
+func (fi *feedInfo) feedURL(format string) string {
+	if fi.base == "" {
+		return ""
+	}
+	for _, f := range formats {
+		if f.name == format {
+			return fi.base + fi.path + "/" + f.file
+		}
+	}
+	return ""
+}
+
+// writeFeed writes the feed data in the given format. The parameter feedURL
+// is the URL of the feed without any page selection.
+func writeFeed(w io.Writer, fd *feedData, format, feedURL string) error {
+	switch format {
+	case formatRSS:
+		return fd.RSS(feedURL).Write(w)
+	case formatAtom:
+		return fd.Atom(feedURL).Write(w)
+	case formatJSON:
+		return fd.JSONFeed(feedURL).Write(w)
+	}
+	return fmt.Errorf("unknown feed format %q", format)
+}
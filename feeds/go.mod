@@ -3,12 +3,20 @@ module zettelstore.de/contrib/feeds
 go 1.26
 
 require (
-	t73f.de/r/webs v0.0.0-20260707123138-a0fd2693c130
+	t73f.de/r/sx v0.0.0-20260707123451-9afa5b03bb8a
+	t73f.de/r/sxwebs v0.0.0-20260707123716-eed127fbf809
 	t73f.de/r/zsc v0.0.0-20260707124142-6e1bc9fd581f
+	t73f.de/r/zsx v0.0.0-20260707123941-614a5dd04107
+	zettelstore.de/contrib/server v0.0.0
+	zettelstore.de/contrib/zstest v0.0.0
 )
 
 require (
-	t73f.de/r/sx v0.0.0-20260707123451-9afa5b03bb8a // indirect
+	t73f.de/r/webs v0.0.0-20260707123138-a0fd2693c130 // indirect
 	t73f.de/r/zero v0.0.0-20260707122001-de8d8b38ab5b // indirect
-	t73f.de/r/zsx v0.0.0-20260707123941-614a5dd04107 // indirect
+)
+
+replace (
+	zettelstore.de/contrib/server => ../server
+	zettelstore.de/contrib/zstest => ../zstest
 )
@@ -0,0 +1,228 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sxwebs/sxhtml"
+	"t73f.de/r/zsc/shtml"
+)
+
+// indexCSS is the style of the start page.
+const indexCSS = `body { font-family: sans-serif; max-width: 50rem; margin: 0 auto; padding: 0 1rem }
+section { border-top: 1px solid #ccc }
+p.formats a, p.topics a { margin-right: .5em }
+time { font-style: italic }
+`
+
+// indexFeed contains all data of a feed that is shown on the start page.
+type indexFeed struct {
+	key      string
+	fi       *feedInfo
+	modified time.Time
+	topics   *feedTopics
+}
+
+// Name returns the name of the feed to be shown.
+func (inf *indexFeed) Name() string { return inf.fi.channel().Title }
+
+// maxIndexFetches is the maximum number of feeds whose data is retrieved
+// concurrently for the start page.
+const maxIndexFetches = 4
+
+// collectIndexFeeds returns the data of all feeds for the start page. Errors
+// when retrieving data from a Zettelstore are logged, but the feed is shown.
+// Tags and roles are retrieved only if there are feeds for them. The data of
+// the feeds is retrieved concurrently, so that a slow Zettelstore does not
+// delay the start page by the number of feeds.
+func collectIndexFeeds(ctx context.Context, feeds *feedSet, withTopics bool) []*indexFeed {
+	keys := feeds.Keys()
+	result := make([]*indexFeed, 0, len(keys))
+	for _, key := range keys {
+		if fi, found := feeds.Get(key); found {
+			result = append(result, &indexFeed{key: key, fi: fi})
+		}
+	}
+
+	sem := make(chan struct{}, maxIndexFetches)
+	var wg sync.WaitGroup
+	for _, inf := range result {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if modified, err := inf.fi.LastModified(ctx); err != nil {
+				slog.Warn("Unable to retrieve feed", "feed", inf.key, "err", err)
+			} else {
+				inf.modified = modified
+			}
+			if withTopics {
+				if ft, err := inf.fi.Topics(ctx); err != nil {
+					slog.Warn("Unable to retrieve tags and roles", "feed", inf.key, "err", err)
+				} else {
+					inf.topics = ft
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return result
+}
+
+func makeRootHandler(feeds *feedSet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = writeIndexPage(w, collectIndexFeeds(r.Context(), feeds, true), "opml")
+	})
+}
+
+// writeIndexPage writes the start page, which lists the given feeds. All
+// links are relative to the start page, so that it can be placed into any
+// directory.
+func writeIndexPage(w io.Writer, infs []*indexFeed, opmlPath string) error {
+	headHTML := sx.MakeList(
+		shtml.SymHead,
+		sx.MakeList(shtml.SymMeta, sx.MakeList(sx.Cons(sxhtml.MakeSymbol("charset"), sx.MakeString("utf-8")))),
+		sx.MakeList(shtml.SymMeta, sx.MakeList(
+			sx.Cons(sxhtml.MakeSymbol("name"), sx.MakeString("viewport")),
+			sx.Cons(sxhtml.MakeSymbol("content"), sx.MakeString("width=device-width, initial-scale=1.0")),
+		)),
+		sx.MakeList(shtml.SymMeta, sx.MakeList(
+			sx.Cons(sxhtml.MakeSymbol("name"), sx.MakeString("generator")),
+			sx.Cons(sxhtml.MakeSymbol("content"), sx.MakeString("Zettel Feeds")),
+		)),
+		sx.MakeList(shtml.SymTitle, sx.MakeString("Zettel Feeds")),
+		sx.MakeList(sxhtml.MakeSymbol("style"), sx.MakeList(sxhtml.SymNoEscape, sx.MakeString(indexCSS))),
+	)
+	curr := headHTML.LastPair()
+	for _, inf := range infs {
+		for _, f := range formats {
+			curr = curr.AppendBang(getAlternateLink(
+				feedPath(inf.key, f.file), f.contentType, inf.Name()+" ("+f.title+")"))
+		}
+	}
+	curr.AppendBang(getAlternateLink(opmlPath, opmlContentType, "All feeds (OPML)"))
+
+	bodyHTML := sx.MakeList(
+		shtml.SymBody,
+		sx.MakeList(sxhtml.MakeSymbol("header"), sx.MakeList(shtml.SymH1, sx.MakeString("Zettel Feeds"))),
+	)
+	curr = bodyHTML.LastPair()
+	for _, inf := range infs {
+		curr = curr.AppendBang(getFeedSection(inf))
+	}
+	curr.AppendBang(sx.MakeList(
+		sxhtml.MakeSymbol("footer"),
+		sx.MakeList(
+			shtml.SymP,
+			getSimpleLink(opmlPath, "Subscribe to all feeds (OPML)"),
+		),
+	))
+
+	g := sxhtml.NewGenerator().SetNewline()
+	return g.WriteHTML(w, sx.MakeList(
+		sxhtml.SymDoctype,
+		sx.MakeList(shtml.SymHTML, headHTML, bodyHTML),
+	))
+}
+
+// getFeedSection returns the part of the start page that describes a feed.
+func getFeedSection(inf *indexFeed) *sx.Pair {
+	fi := inf.fi
+	result := sx.MakeList(
+		sxhtml.MakeSymbol("section"),
+		sx.MakeList(shtml.SymH2, getSimpleLink(feedPath(inf.key, formats[0].file), inf.Name())),
+	)
+	curr := result.LastPair()
+	if description := fi.channel().Description; description != "" {
+		curr = curr.AppendBang(sx.MakeList(shtml.SymP, sx.MakeString(description)))
+	}
+	if !inf.modified.IsZero() {
+		curr = curr.AppendBang(sx.MakeList(
+			shtml.SymP,
+			sx.MakeString("Last update: "),
+			sx.MakeList(
+				sxhtml.MakeSymbol("time"),
+				sx.MakeList(sx.Cons(sxhtml.MakeSymbol("datetime"), sx.MakeString(inf.modified.Format(time.RFC3339)))),
+				sx.MakeString(inf.modified.Format("2006-01-02 15:04")),
+			),
+		))
+	}
+	formatsHTML := sx.MakeList(shtml.SymP, getClassAttr("formats"))
+	fcurr := formatsHTML.LastPair()
+	for _, f := range formats {
+		fcurr = fcurr.AppendBang(getSimpleLink(feedPath(inf.key, f.file), f.title))
+	}
+	curr = curr.AppendBang(formatsHTML)
+	if ft := inf.topics; ft != nil {
+		if topicsHTML := getTopics(inf.key, "Tags: ", filterTag, "#", ft.Tags); topicsHTML != nil {
+			curr = curr.AppendBang(topicsHTML)
+		}
+		if topicsHTML := getTopics(inf.key, "Roles: ", filterRole, "", ft.Roles); topicsHTML != nil {
+			curr.AppendBang(topicsHTML)
+		}
+	}
+	return result
+}
+
+func getTopics(key, heading, kind, prefix string, topics []feedTopic) *sx.Pair {
+	if len(topics) == 0 {
+		return nil
+	}
+	result := sx.MakeList(shtml.SymP, getClassAttr("topics"), sx.MakeString(heading))
+	curr := result.LastPair()
+	for _, t := range topics {
+		curr = curr.AppendBang(getSimpleLink(
+			feedPath(key, kind+"/"+url.PathEscape(t.Name)+"/"),
+			prefix+t.Name+" ("+strconv.Itoa(t.Count)+")",
+		))
+	}
+	return result
+}
+
+// feedPath returns the path of a file of a feed, relative to the start page.
+func feedPath(key, file string) string { return "./" + url.PathEscape(key) + "/" + file }
+
+// getAlternateLink returns a link to an alternate representation of the
+// page, used for feed autodiscovery.
+func getAlternateLink(href, typ, title string) *sx.Pair {
+	return sx.MakeList(
+		sxhtml.MakeSymbol("link"),
+		sx.MakeList(
+			sx.Cons(shtml.SymAttrRel, sx.MakeString("alternate")),
+			sx.Cons(shtml.SymAttrType, sx.MakeString(typ)),
+			sx.Cons(sxhtml.MakeSymbol("title"), sx.MakeString(title)),
+			sx.Cons(shtml.SymAttrHref, sx.MakeString(href)),
+		))
+}
+
+func getSimpleLink(href, text string) *sx.Pair {
+	return sx.MakeList(
+		shtml.SymA,
+		sx.MakeList(sx.Cons(shtml.SymAttrHref, sx.MakeString(href))),
+		sx.MakeString(text),
+	)
+}
+
+func getClassAttr(class string) *sx.Pair {
+	return sx.MakeList(sx.Cons(shtml.SymAttrClass, sx.MakeString(class)))
+}
@@ -0,0 +1,120 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonFeed is a feed according to the JSON Feed specification, version 1.1.
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	NextURL     string           `json:"next_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Hubs        []jsonFeedHub    `json:"hubs,omitempty"`
+	Items       []*jsonFeedItem  `json:"items"`
+}
+
+type jsonFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url,omitempty"`
+	Title         string               `json:"title,omitempty"`
+	ContentHTML   string               `json:"content_html,omitempty"`
+	ContentText   string               `json:"content_text,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	DateModified  string               `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Tags          []string             `json:"tags,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MIMEType    string `json:"mime_type"`
+	SizeInBytes int    `json:"size_in_bytes,omitempty"`
+}
+
+// JSONFeed returns the data as a JSON feed. The parameter feedURL is the URL
+// of the feed without any page selection.
+func (fd *feedData) JSONFeed(feedURL string) *jsonFeed {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       fd.Title,
+		HomePageURL: fd.Link,
+		FeedURL:     feedURL,
+		Description: fd.Description,
+		Language:    fd.Language,
+		Items:       make([]*jsonFeedItem, 0, len(fd.Items)),
+	}
+	if next, ok := fd.NextPage(); ok && feedURL != "" {
+		feed.NextURL = next.URL(feedURL)
+	}
+	if fd.Hub != "" && fd.Page == currentPage {
+		feed.Hubs = []jsonFeedHub{{Type: "WebSub", URL: fd.Hub}}
+	}
+	if person := makeAtomPerson(fd.Author, fd.ManagingEditor, ""); person != nil {
+		feed.Authors = []jsonFeedAuthor{{Name: person.Name}}
+	}
+	for _, item := range fd.Items {
+		jItem := jsonFeedItem{
+			ID:          item.Link,
+			URL:         item.Link,
+			Title:       item.Title,
+			ContentHTML: item.Content,
+			Tags:        item.Tags,
+		}
+		if jItem.ContentHTML == "" {
+			// One of content_html or content_text must be present.
+			jItem.ContentText = item.Title
+		}
+		if !item.Published.IsZero() {
+			jItem.DatePublished = item.Published.Format(time.RFC3339)
+		}
+		if !item.Updated.IsZero() && !item.Updated.Equal(item.Published) {
+			jItem.DateModified = item.Updated.Format(time.RFC3339)
+		}
+		if item.Author != "" {
+			jItem.Authors = []jsonFeedAuthor{{Name: item.Author}}
+		}
+		for _, fe := range item.Enclosures {
+			jItem.Attachments = append(jItem.Attachments, jsonFeedAttachment{URL: fe.URL, MIMEType: fe.Type, SizeInBytes: fe.Length})
+		}
+		feed.Items = append(feed.Items, &jItem)
+	}
+	return &feed
+}
+
+// Write the JSON feed.
+func (jf *jsonFeed) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jf)
+}
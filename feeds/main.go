@@ -15,214 +15,335 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"maps"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"slices"
+	"syscall"
 	"time"
 
-	"t73f.de/r/webs/feed/rss"
-	"t73f.de/r/zsc/client"
+	"t73f.de/r/zsc/domain/id"
 	"t73f.de/r/zsc/domain/meta"
-	"t73f.de/r/zsc/webapi"
+	"zettelstore.de/contrib/server"
 )
 
-//************
-//
-// TODO: this is just a POC, there is no real architecture, tests, etc,
-//
-//************
-
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		os.Exit(runBuild(os.Args[2:]))
+	}
 	listenAddress := flag.String("l", ":23110", "Listen address")
+	serverURL := flag.String("u", "", "URL of the server, as used by clients (default: derived from listen address)")
+	configFile := flag.String("c", "", "File with feed definitions")
+	reloadInterval := flag.Duration("r", time.Minute, "Interval to check for changed feed definitions")
+	timeout := flag.Duration("t", 30*time.Second, "Timeout for retrieving data from a Zettelstore")
+	pollInterval := flag.Duration("p", time.Minute, "Interval to check subscribed feeds for new zettel, 0 disables the WebSub hub")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn, error")
+	logFormat := flag.String("log-format", "text", "Log format: text, json")
 	flag.Usage = func() {
 		out := flag.CommandLine.Output()
 		_, _ = fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
 		flag.PrintDefaults()
+		_, _ = io.WriteString(out, "  [URL] URL of Zettelstore with feed definitions, if no file is given\n")
+		_, _ = fmt.Fprintf(out, "Use \"%s build -h\" to write the feeds into files.\n", os.Args[0])
 	}
 	flag.Parse()
+	if err := server.SetupLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to configure logging: %v\n", err)
+		os.Exit(2)
+	}
 
-	feeds := buildFeeds()
+	src, err := makeFeedSource(*configFile, flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to determine feed definitions: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	base, err := makeServerBase(*serverURL, *listenAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid server URL: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	feeds := newFeedSet(src, base, *timeout)
+	var h *hub
+	if *pollInterval > 0 {
+		feeds.hub = base + hubPath
+		h = newHub(feeds)
+	}
+	if _, err = feeds.Reload(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load feed definitions from %s: %v\n", src, err)
+		os.Exit(2)
+	}
+	go feeds.Watch(ctx, *reloadInterval)
+	if h != nil {
+		go h.Watch(ctx, *pollInterval)
+	}
 
-	http.Handle("GET /{$}", makeRootHandler(feeds))
-	http.Handle("GET /{feed}/{$}", makeFeedHandler(feeds))
-	fmt.Println("Listening:", *listenAddress)
-	_ = http.ListenAndServe(*listenAddress, nil)
+	mux := makeServeMux(feeds, h)
+	if err = server.Serve(ctx, *listenAddress, server.WithTimeout(*timeout, mux)); err != nil {
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
 }
 
-func makeRootHandler(feeds feeds) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		keys := slices.Sorted(maps.Keys(feeds))
-
-		w.Header().Add("Content-Type", "text/html")
-		_, _ = io.WriteString(w, `<!DOCTYPE html>
-<head>
-<title>Zettel Feeds</title>
-</head>
-<body>
-<h1>Zettel Feeds</h1>
-<ul>
-`)
-		for _, key := range keys {
-			name := feeds[key].Title
-			if name == "" {
-				name = key
-			}
-			_, _ = fmt.Fprintf(w, "<li><a href=\"/%s/\">%s</a></li>\n", key, name)
+// makeServeMux returns the handler for all URL paths of the server. Without a
+// hub, subscriptions are not accepted.
+func makeServeMux(feeds *feedSet, h *hub) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", countRequests("root", makeRootHandler(feeds)))
+	mux.Handle("GET /opml", countRequests("opml", makeOPMLHandler(feeds)))
+	if h != nil {
+		mux.Handle("POST "+hubPath, countRequests("hub", h))
+	}
+	mux.Handle("GET /status", makeStatusHandler(feeds.reg))
+	mux.Handle("GET /healthz", makeHealthHandler())
+	mux.Handle("GET /readyz", makeReadyHandler(feeds.reg))
+	mux.Handle("GET /metrics", makeMetricsHandler())
+	for _, prefix := range []string{"/{feed}", "/{feed}/" + filterTag + "/{tag}", "/{feed}/" + filterRole + "/{role}"} {
+		mux.Handle("GET "+prefix+"/{$}", countRequests("feed", makeFeedHandler(feeds, "")))
+		for _, f := range formats {
+			mux.Handle("GET "+prefix+"/"+f.file, countRequests(f.name, makeFeedHandler(feeds, f.name)))
 		}
-		_, _ = io.WriteString(w, "</ul>\n</body>\n</html>")
-	})
+	}
+	return mux
 }
 
-func makeFeedHandler(feeds feeds) http.Handler {
+func makeFeedHandler(feeds *feedSet, format string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := r.PathValue("feed")
-		fi, ok := feeds[key]
+		fi, ok := feeds.Get(r.PathValue("feed"))
 		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-		feed, err := fi.retrieve(r.Context())
+		kind, value := filterTag, r.PathValue("tag")
+		if value == "" {
+			kind, value = filterRole, r.PathValue("role")
+		}
+		if value != "" {
+			ffi, err := fi.Filtered(r.Context(), kind, value)
+			if errors.Is(err, errInvalidFilter) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				slog.Error("Unable to retrieve topics", "feed", fi.name, "err", err)
+				stats.FeedError(fi.name)
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			fi = ffi
+		}
+		pg, err := parsePage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		feedFormat := format
+		if feedFormat == "" {
+			feedFormat = negotiateFormat(r.Header.Get("Accept"))
+		}
+		rf, err := fi.render(r.Context(), pg, feedFormat)
+		if errors.Is(err, errPageNotFound) {
+			http.NotFound(w, r)
+			return
+		}
 		if err != nil {
-			log.Println("FERR", key, err)
+			slog.Error("Unable to retrieve feed", "feed", fi.name, "err", err)
+			stats.FeedError(fi.name)
 			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
 			return
 		}
 
-		w.Header().Add("content-type", "application/rss+xml")
-		_ = feed.Write(w)
+		h := w.Header()
+		h.Set("Content-Type", contentType(feedFormat))
+		h.Set("ETag", rf.etag)
+		h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(fi.cacheTTL().Seconds())))
+		if format == "" {
+			h.Set("Vary", "Accept")
+		}
+		if self := fi.feedURL(feedFormat); pg == currentPage && self != "" {
+			if fi.hub != "" {
+				h.Set("Link", fmt.Sprintf(`<%s>; rel="hub", <%s>; rel="self"`, fi.hub, self))
+			} else {
+				h.Set("Link", fmt.Sprintf(`<%s>; rel="self"`, self))
+			}
+		}
+		http.ServeContent(w, r, "", rf.modified, bytes.NewReader(rf.data))
 	})
 }
 
-func buildFeeds() feeds {
-	return feeds{
-		/*
-			"local": {
-				Title:       "Localhost",
-				URL:         "http://localhost:23123/",
-				Description: "My local Zettelstore, for testing",
-				Language:    "de",
-				Limit:       -1,
-			},
-			//*/
-		"das": {
-			Title:       "Agiles Studieren",
-			URL:         "https://agiles-studieren.de/",
-			Description: "Alles über Agiles Studieren, eine innovative Lehr-/Lernmethode",
-			Language:    "de",
-			Copyright:   "2014-present Prof. Dr. Detlef Stern",
-			WebMaster:   "webmaster@agiles-studieren.de",
-			TTL:         60,
-		},
-		"manual": {
-			Title:          "Zettelstore Manual",
-			URL:            "https://zettelstore.de/manual/",
-			Description:    "Latest official version of the Zettelstore manual",
-			Language:       "en",
-			Copyright:      "2020-present Detlef Stern",
-			ManagingEditor: "ds@zettelstore.de",
-			WebMaster:      "ds@zettelstore.de",
-			TTL:            60,
-		},
-	}
-
-}
-
 type feeds map[string]*feedInfo
 type feedInfo struct {
+	name           string
 	Title          string
 	URL            string
-	Description    string // ZS API CALL??
-	Language       string // ZS API CALL??
-	Copyright      string // ZS API CALL??
+	Link           string
+	Description    string
+	Language       string
+	Copyright      string
+	Author         string
 	ManagingEditor string
 	WebMaster      string
 	TTL            int
 	Limit          int
+	Query          string
+	Username       string
+	Password       string
+
+	base    string // URL of the server or of the published directory, may be empty
+	path    string // URL path of the feed, relative to base
+	hub     string // URL of the WebSub hub, empty if there is none
+	filter  string // Tag or role of a filtered feed
+	static  bool   // Feed is written to files, there is no server
+	up      *upstream
+	cache   feedCache
+	filters feedFilters
 }
 
-func (fi *feedInfo) retrieve(ctx context.Context) (*rss.Feed, error) {
-	u, err := url.Parse(fi.URL)
-	if err != nil {
-		return nil, err
+// feedData is the format independent data of a feed.
+type feedData struct {
+	Name           string // Name of the feed, including a filter
+	Title          string
+	Link           string
+	Description    string
+	Language       string
+	Copyright      string
+	Author         string
+	ManagingEditor string
+	WebMaster      string
+	TTL            int
+	Published      time.Time // Publishing date of newest item
+	Updated        time.Time // Last modification of any item
+	Built          time.Time
+	Page           feedPage
+	Pages          int    // Number of pages, if the feed is paged
+	Archives       int    // Number of complete archive documents
+	Hub            string // URL of the WebSub hub, empty if there is none
+	Items          []*feedItem
+}
+
+// LastModified returns the time of the last change of the feed data.
+func (fd *feedData) LastModified() time.Time {
+	if fd.Updated.IsZero() {
+		return fd.Built
 	}
-	c := client.NewClient(u)
+	return fd.Updated
+}
+
+// feedItem is the format independent data of a feed item, i.e. of a zettel.
+type feedItem struct {
+	Zid        id.Zid
+	Title      string
+	Link       string
+	Author     string
+	Tags       []string
+	Content    string // HTML content, if available
+	Enclosures []*feedEnclosure
+	Published  time.Time
+	Updated    time.Time
+}
 
-	ver, err := c.GetVersionInfo(ctx)
+func (fi *feedInfo) retrieve(ctx context.Context, pg feedPage) (*feedData, error) {
+	c, withAuth, err := fi.up.Client(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve version: %w", err)
-	}
-	if ver.Major <= 1 || ver.Major > 2 {
-		return nil, fmt.Errorf("unsupported version: %v", ver)
+		return nil, err
 	}
+	u := fi.up.base
+	start := time.Now()
+	defer func() { stats.Upstream(u.String(), time.Since(start)) }()
 
-	const defaultSelect = meta.KeyRole + webapi.SearchOperatorNotEqual + meta.ValueRoleConfiguration // config
-	var limit = fi.Limit
-	if limit == 0 {
-		limit = 30
-	} else if limit < 0 {
-		limit = 0
+	pages, archives := 1, 0
+	if size := fi.pageSize(); size > 0 && !fi.static {
+		n, errCount := fi.zettelCount(ctx, c, withAuth)
+		if errCount != nil {
+			return nil, errCount
+		}
+		pages, archives = max((n+size-1)/size, 1), n/size
+	}
+	if pg.Archive && pg.Num > archives || !pg.Archive && pg.Num > pages {
+		return nil, errPageNotFound
 	}
 
-	defaultQuery := defaultSelect +
-		" " + webapi.OrderDirective + " " + webapi.ReverseDirective + " " + meta.KeyPublished +
-		" " + webapi.LimitDirective + fmt.Sprintf(" %d", limit)
-	_, _, ml, err := c.QueryZettelData(ctx, defaultQuery)
+	_, _, ml, err := c.QueryZettelData(ctx, fi.buildQuery(withAuth, pg))
 	if err != nil {
 		return nil, fmt.Errorf("unable to query zettel: %w", err)
 	}
+	if pg.Archive {
+		slices.Reverse(ml) // Archives are retrieved oldest first
+	}
 
-	description := fi.Description
-	if description == "" {
-		description = "Missing Description"
+	ch := fi.channel()
+	if ch.Description == "" {
+		ch.Description = "Missing Description"
 	}
-	feed := rss.Feed{
-		Title:          fi.Title,
-		Link:           u.String(), // poss overwritten by config
-		Description:    description,
-		Language:       fi.Language,
-		Copyright:      fi.Copyright,
+	fd := feedData{
+		Name:           fi.name,
+		Title:          ch.Title,
+		Link:           ch.Link,
+		Description:    ch.Description,
+		Language:       ch.Language,
+		Copyright:      ch.Copyright,
+		Author:         fi.Author,
 		ManagingEditor: fi.ManagingEditor,
 		WebMaster:      fi.WebMaster,
-		PubDate:        "", // will be set below
-		LastBuildDate:  rss.RFC822Date(time.Now()),
-		Generator:      "Zettel Feeds",
 		TTL:            fi.TTL,
-		Items:          make([]*rss.Item, 0, len(ml)),
+		Built:          time.Now(),
+		Page:           pg,
+		Pages:          pages,
+		Archives:       archives,
+		Hub:            fi.hub,
+		Items:          make([]*feedItem, 0, len(ml)),
 	}
 	for _, mr := range ml {
-		item := rss.Item{
-			Description: rss.CData{
-				Data: "", // later: some sz -> sxhtml transformation, (w/o internal links?)
-			},
-		}
 		m := mr.Meta
-		if item.Title = m[meta.KeyTitle]; item.Title == "" {
-			item.Title = mr.ID.String()
+		if !isVisible(m, withAuth) {
+			slog.Warn("Non-public zettel ignored", "feed", fi.name, "zid", mr.ID, "visibility", m[meta.KeyVisibility])
+			continue
 		}
-
-		item.Category = append(item.Category, meta.Value(m[meta.KeyTags]).AsTags()...)
-
-		zurl := c.NewURLBuilder('h').SetZid(mr.ID).String()
-		item.Link = zurl
-		item.GUID = &rss.GUID{
-			IsPermaLink: true,
-			Value:       zurl,
+		item := feedItem{
+			Zid:    mr.ID,
+			Title:  m[meta.KeyTitle],
+			Link:   c.NewURLBuilder('h').SetZid(mr.ID).String(),
+			Author: m[KeyAuthor],
+			Tags:   meta.Value(m[meta.KeyTags]).AsTags(),
 		}
-		pubDate, ok := meta.Value(m[meta.KeyPublished]).AsTime()
-		if ok {
-			item.PubDate = rss.RFC822Date(pubDate)
-			if feed.PubDate == "" {
-				feed.PubDate = item.PubDate
+		if item.Title == "" {
+			item.Title = mr.ID.String()
+		}
+		if pubDate, ok := meta.Value(m[meta.KeyPublished]).AsTime(); ok {
+			item.Published = pubDate
+			if fd.Published.IsZero() {
+				fd.Published = pubDate
 			}
 		}
-		feed.Items = append(feed.Items, &item)
+		item.Updated = item.Published
+		if modDate, ok := meta.Value(m[meta.KeyModified]).AsTime(); ok && modDate.After(item.Updated) {
+			item.Updated = modDate
+		}
+		if item.Updated.After(fd.Updated) {
+			fd.Updated = item.Updated
+		}
+		fd.Items = append(fd.Items, &item)
+	}
+
+	cg := newContentGenerator(ctx, c, ch.Language, withAuth, &fi.up.media)
+	for _, item := range fd.Items {
+		content, enclosures, errContent := cg.Content(item.Zid)
+		item.Enclosures = enclosures
+		if errContent != nil {
+			slog.Warn("Unable to render zettel content", "feed", fi.name, "zid", item.Zid, "err", errContent)
+			continue
+		}
+		item.Content = content
 	}
-	return &feed, nil
+	return &fd, nil
 }
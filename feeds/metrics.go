@@ -0,0 +1,168 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics collects some counters to observe the operation of the server.
+type metrics struct {
+	mx              sync.Mutex
+	requests        map[[2]string]uint64 // handler, status code
+	cacheHits       map[string]uint64
+	cacheMisses     map[string]uint64
+	feedErrors      map[string]uint64
+	upstreamCalls   map[string]uint64
+	upstreamSeconds map[string]float64
+}
+
+// stats contains the metrics of the running server.
+var stats = newMetrics()
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:        map[[2]string]uint64{},
+		cacheHits:       map[string]uint64{},
+		cacheMisses:     map[string]uint64{},
+		feedErrors:      map[string]uint64{},
+		upstreamCalls:   map[string]uint64{},
+		upstreamSeconds: map[string]float64{},
+	}
+}
+
+func (m *metrics) Request(handler string, code int) {
+	m.mx.Lock()
+	m.requests[[2]string{handler, strconv.Itoa(code)}]++
+	m.mx.Unlock()
+}
+
+func (m *metrics) Cache(feed string, hit bool) {
+	m.mx.Lock()
+	if hit {
+		m.cacheHits[feed]++
+	} else {
+		m.cacheMisses[feed]++
+	}
+	m.mx.Unlock()
+}
+
+func (m *metrics) FeedError(feed string) {
+	m.mx.Lock()
+	m.feedErrors[feed]++
+	m.mx.Unlock()
+}
+
+func (m *metrics) Upstream(base string, d time.Duration) {
+	m.mx.Lock()
+	m.upstreamCalls[base]++
+	m.upstreamSeconds[base] += d.Seconds()
+	m.mx.Unlock()
+}
+
+// Write the metrics in the Prometheus text format.
+func (m *metrics) Write(w io.Writer) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	writeHeader(w, "feeds_requests_total", "counter", "Number of HTTP requests.")
+	for _, key := range slices.SortedFunc(maps.Keys(m.requests), func(a, b [2]string) int {
+		return strings.Compare(a[0]+" "+a[1], b[0]+" "+b[1])
+	}) {
+		_, _ = fmt.Fprintf(w, "feeds_requests_total{handler=%q,code=%q} %d\n", key[0], key[1], m.requests[key])
+	}
+	writeCounters(w, "feeds_cache_hits_total", "Number of feed requests served from cache.", "feed", m.cacheHits)
+	writeCounters(w, "feeds_cache_misses_total", "Number of feed requests that needed the Zettelstore.", "feed", m.cacheMisses)
+	writeCounters(w, "feeds_errors_total", "Number of feed requests that failed.", "feed", m.feedErrors)
+	writeCounters(w, "feeds_upstream_requests_total", "Number of retrievals from a Zettelstore.", "upstream", m.upstreamCalls)
+	writeHeader(w, "feeds_upstream_seconds_total", "counter", "Time spent for retrievals from a Zettelstore.")
+	for _, key := range slices.Sorted(maps.Keys(m.upstreamSeconds)) {
+		_, _ = fmt.Fprintf(w, "feeds_upstream_seconds_total{upstream=%q} %g\n", key, m.upstreamSeconds[key])
+	}
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeCounters(w io.Writer, name, help, label string, counters map[string]uint64) {
+	writeHeader(w, name, "counter", help)
+	for _, key := range slices.Sorted(maps.Keys(counters)) {
+		_, _ = fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, key, counters[key])
+	}
+}
+
+// statusRecorder remembers the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.code = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// countRequests counts the requests of a handler, grouped by status code.
+func countRequests(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sr := statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(&sr, r)
+		stats.Request(name, sr.code)
+	})
+}
+
+func makeMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		stats.Write(w)
+	})
+}
+
+func makeHealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, "ok\n")
+	})
+}
+
+// makeReadyHandler reports whether all upstream Zettelstores answered their
+// last version check.
+func makeReadyHandler(reg *upstreamRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		var failed []upstreamStatus
+		for _, st := range reg.Status() {
+			if st.Error != "" || st.Checked.IsZero() {
+				failed = append(failed, st)
+			}
+		}
+		if len(failed) == 0 {
+			_, _ = io.WriteString(w, "ok\n")
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, st := range failed {
+			_, _ = fmt.Fprintf(w, "%s: %s\n", st.URL, st.Error)
+		}
+	})
+}
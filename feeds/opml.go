@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// opmlContentType is the MIME type of an OPML document.
+const opmlContentType = "text/x-opml; charset=utf-8"
+
+// opmlDocument is a list of feeds, according to the OPML 2.0 specification.
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Head    opmlHead      `xml:"head"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	DocsURL     string `xml:"docs,omitempty"`
+}
+
+type opmlOutline struct {
+	Type        string `xml:"type,attr"`
+	Text        string `xml:"text,attr"`
+	Title       string `xml:"title,attr,omitempty"`
+	Description string `xml:"description,attr,omitempty"`
+	Language    string `xml:"language,attr,omitempty"`
+	XMLURL      string `xml:"xmlUrl,attr"`
+	HTMLURL     string `xml:"htmlUrl,attr,omitempty"`
+}
+
+// makeOPMLDocument returns the OPML document that lists all feeds, with their
+// absolute URLs.
+func makeOPMLDocument(feeds *feedSet) *opmlDocument {
+	doc := opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "Zettel Feeds",
+			DateCreated: time.Now().Format(time.RFC1123Z),
+			DocsURL:     "https://opml.org/spec2.opml",
+		},
+	}
+	for _, key := range feeds.Keys() {
+		fi, found := feeds.Get(key)
+		if !found {
+			continue
+		}
+		ch := fi.channel()
+		doc.Body = append(doc.Body, opmlOutline{
+			Type:        "rss",
+			Text:        ch.Title,
+			Title:       ch.Title,
+			Description: ch.Description,
+			Language:    ch.Language,
+			XMLURL:      fi.feedURL(formats[0].name),
+			HTMLURL:     ch.Link,
+		})
+	}
+	return &doc
+}
+
+// Write the OPML document as XML.
+func (od *opmlDocument) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(od); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// makeOPMLHandler returns a handler that lists all feeds as an OPML document,
+// so that a feed reader can subscribe to all of them at once.
+func makeOPMLHandler(feeds *feedSet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", opmlContentType)
+		if err := makeOPMLDocument(feeds).Write(w); err != nil {
+			slog.Error("Unable to write OPML", "err", err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Query parameters to select a page of a feed.
+const (
+	queryKeyPage    = "page"
+	queryKeyArchive = "archive"
+)
+
+// historyNamespace is the XML namespace of RFC 5005 "Feed Paging and Archiving".
+const historyNamespace = "http://purl.org/syndication/history/1.0"
+
+// errPageNotFound is returned, if a feed does not contain the requested page.
+var errPageNotFound = errors.New("page not found")
+
+// feedPage identifies a part of a feed, according to RFC 5005.
+//
+// Pages are counted from the newest zettel, the first page is the feed
+// itself. Their content changes whenever a new zettel is published. Archive
+// documents are counted from the oldest zettel, and contain a fixed number of
+// zettel. Therefore, their content does not change, and feed readers can
+// cache them.
+type feedPage struct {
+	Archive bool
+	Num     int // starting with 1
+}
+
+// currentPage is the feed itself.
+var currentPage = feedPage{Num: 1}
+
+// parsePage returns the page that is requested by the query of an URL.
+func parsePage(r *http.Request) (feedPage, error) {
+	q := r.URL.Query()
+	page, archive := q.Get(queryKeyPage), q.Get(queryKeyArchive)
+	switch {
+	case page == "" && archive == "":
+		return currentPage, nil
+	case page != "" && archive != "":
+		return feedPage{}, fmt.Errorf("only one of %q and %q is allowed", queryKeyPage, queryKeyArchive)
+	case page != "":
+		num, err := strconv.Atoi(page)
+		if err != nil || num < 1 {
+			return feedPage{}, fmt.Errorf("invalid page %q", page)
+		}
+		return feedPage{Num: num}, nil
+	}
+	num, err := strconv.Atoi(archive)
+	if err != nil || num < 1 {
+		return feedPage{}, fmt.Errorf("invalid archive %q", archive)
+	}
+	return feedPage{Archive: true, Num: num}, nil
+}
+
+// URL returns the URL of the page, based on the URL of the feed.
+func (pg feedPage) URL(feedURL string) string {
+	if pg.Archive {
+		return feedURL + "?" + queryKeyArchive + "=" + strconv.Itoa(pg.Num)
+	}
+	if pg.Num <= 1 {
+		return feedURL
+	}
+	return feedURL + "?" + queryKeyPage + "=" + strconv.Itoa(pg.Num)
+}
+
+// pageLink is a link to another page of a feed.
+type pageLink struct {
+	Rel  string
+	Page feedPage
+}
+
+// PageLinks returns the links to other pages of the feed.
+//
+// The feed itself and its pages are linked as a paged feed ("first",
+// "previous", "next", "last"). The feed itself and the archive documents are
+// linked as an archived feed ("current", "prev-archive", "next-archive").
+func (fd *feedData) PageLinks() []pageLink {
+	pg := fd.Page
+	var result []pageLink
+	if pg.Archive {
+		result = append(result, pageLink{"current", currentPage})
+		if pg.Num > 1 {
+			result = append(result, pageLink{"prev-archive", feedPage{Archive: true, Num: pg.Num - 1}})
+		}
+		if pg.Num < fd.Archives {
+			result = append(result, pageLink{"next-archive", feedPage{Archive: true, Num: pg.Num + 1}})
+		}
+		return result
+	}
+	if fd.Pages > 1 {
+		result = append(result, pageLink{"first", currentPage})
+		if pg.Num > 1 {
+			result = append(result, pageLink{"previous", feedPage{Num: pg.Num - 1}})
+		}
+		if pg.Num < fd.Pages {
+			result = append(result, pageLink{"next", feedPage{Num: pg.Num + 1}})
+		}
+		result = append(result, pageLink{"last", feedPage{Num: fd.Pages}})
+	}
+	if pg.Num == 1 && fd.Archives > 0 {
+		result = append(result, pageLink{"prev-archive", feedPage{Archive: true, Num: fd.Archives}})
+	}
+	return result
+}
+
+// NextPage returns the page with older zettel, if there is one.
+func (fd *feedData) NextPage() (feedPage, bool) {
+	pg := fd.Page
+	if pg.Archive {
+		return feedPage{Archive: true, Num: pg.Num - 1}, pg.Num > 1
+	}
+	return feedPage{Num: pg.Num + 1}, pg.Num < fd.Pages
+}
@@ -0,0 +1,186 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"t73f.de/r/zsc/domain/meta"
+	"t73f.de/r/zsc/webapi"
+)
+
+// defaultSelect is used, if a feed does not specify its own query.
+const defaultSelect = meta.KeyRole + webapi.SearchOperatorNotEqual + meta.ValueRoleConfiguration // config
+
+// defaultLimit is the number of feed items, if no limit is given.
+const defaultLimit = 30
+
+// orKeyword separates alternative search terms of a query.
+const orKeyword = "OR"
+
+// checkQuery validates the query of a feed definition. A search term for the
+// visibility is only allowed, if it selects public zettel.
+func checkQuery(query string) error {
+	if strings.Contains(query, "|") {
+		return errors.New("query must not contain actions")
+	}
+	for _, word := range strings.Fields(query) {
+		key, op, val, found := cutSearchTerm(word)
+		if !found || key != meta.KeyVisibility {
+			continue
+		}
+		if (op != webapi.SearchOperatorHas && op != webapi.SearchOperatorEqual) || val != meta.ValueVisibilityPublic {
+			return errors.New("query must not select non-public zettel")
+		}
+	}
+	return nil
+}
+
+// searchOperatorExist selects zettel that have a value for a metadata key.
+const searchOperatorExist = "?"
+
+// searchOperatorChars contains all characters of a search operator,
+// including the negation.
+const searchOperatorChars = "!:=<>~[]?"
+
+// cutSearchTerm splits a search term into key, operator, and value, if it
+// compares a metadata key with a value.
+func cutSearchTerm(word string) (string, string, string, bool) {
+	pos := strings.IndexAny(word, searchOperatorChars)
+	if pos <= 0 {
+		return "", "", "", false
+	}
+	rest := strings.TrimLeft(word[pos:], searchOperatorChars)
+	return strings.ToLower(word[:pos]), word[pos : len(word)-len(rest)], rest, true
+}
+
+// selectQuery returns the query to select the zettel of a feed, without
+// additional directives. A strict query selects only zettel that are
+// explicitly public, which is needed if the client is authenticated and may
+// see more zettel.
+func (fi *feedInfo) selectQuery(strict bool) string {
+	query := strings.TrimSpace(fi.Query)
+	if query == "" {
+		query = defaultSelect
+	}
+	if strict {
+		query = restrictQuery(query, meta.KeyVisibility+webapi.SearchOperatorEqual+meta.ValueVisibilityPublic)
+	}
+	return query
+}
+
+// restrictQuery adds a search term to every alternative of a query. It is
+// placed at the end of each alternative, i.e. before an OR, and before the
+// first directive.
+func restrictQuery(query, term string) string {
+	words := strings.Fields(query)
+	result := make([]string, 0, len(words)+2)
+	inTerms := true
+	for _, word := range words {
+		if inTerms {
+			switch word {
+			case orKeyword:
+				result = append(result, term)
+			case webapi.OrderDirective, webapi.PickDirective, webapi.LimitDirective, webapi.OffsetDirective:
+				result = append(result, term)
+				inTerms = false
+			}
+		}
+		result = append(result, word)
+	}
+	if inTerms {
+		result = append(result, term)
+	}
+	return strings.Join(result, " ")
+}
+
+// stripDirectives returns the search terms of a query, without any directive.
+func stripDirectives(query string) string {
+	words := strings.Fields(query)
+	for i, word := range words {
+		switch word {
+		case webapi.OrderDirective, webapi.PickDirective, webapi.LimitDirective, webapi.OffsetDirective:
+			return strings.Join(words[:i], " ")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// buildQuery returns the query to retrieve the zettel of a page of a feed.
+// Directives that are already part of the feed query are honored; otherwise
+// the zettel are ordered by their publishing date, and the number of zettel
+// is limited.
+func (fi *feedInfo) buildQuery(strict bool, pg feedPage) string {
+	hasOrder, hasLimit := false, false
+	for _, word := range strings.Fields(fi.Query) {
+		switch word {
+		case webapi.OrderDirective, webapi.PickDirective:
+			hasOrder = true
+		case webapi.LimitDirective:
+			hasLimit = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fi.selectQuery(strict))
+	if pg.Archive {
+		sb.WriteString(" " + webapi.OrderDirective + " " + meta.KeyPublished)
+	} else if !hasOrder {
+		sb.WriteString(" " + webapi.OrderDirective + " " + webapi.ReverseDirective + " " + meta.KeyPublished)
+	}
+	if offset := (pg.Num - 1) * fi.pageSize(); offset > 0 {
+		sb.WriteString(" " + webapi.OffsetDirective + " " + strconv.Itoa(offset))
+	}
+	if !hasLimit {
+		limit := fi.Limit
+		if limit == 0 {
+			limit = defaultLimit
+		} else if limit < 0 {
+			limit = 0
+		}
+		sb.WriteString(" " + webapi.LimitDirective + " " + strconv.Itoa(limit))
+	}
+	return sb.String()
+}
+
+// pageSize returns the number of zettel of a page, or zero, if the feed
+// cannot be divided into pages. This is the case, if the feed query contains
+// its own directives, or if the number of zettel is not limited.
+func (fi *feedInfo) pageSize() int {
+	for _, word := range strings.Fields(fi.Query) {
+		switch word {
+		case webapi.OrderDirective, webapi.PickDirective, webapi.LimitDirective, webapi.OffsetDirective:
+			return 0
+		}
+	}
+	if fi.Limit < 0 {
+		return 0
+	}
+	if fi.Limit == 0 {
+		return defaultLimit
+	}
+	return fi.Limit
+}
+
+// isVisible returns false, if the zettel metadata state that the zettel is
+// not public. If strict, the zettel must be explicitly public.
+func isVisible(m map[string]string, strict bool) bool {
+	vis, found := m[meta.KeyVisibility]
+	if !found {
+		return !strict
+	}
+	return vis == meta.ValueVisibilityPublic
+}
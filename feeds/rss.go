@@ -0,0 +1,164 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// rssFeed is a RSS 2.0 feed. Links to the feed itself and to other pages of
+// the feed are given as Atom links.
+//
+// The feed is not encoded with package t73f.de/r/webs/feed/rss, because its
+// types contain only the elements of RSS 2.0 itself. They cannot carry
+// elements of other namespaces, like the atom:link elements and the
+// fh:archive element of RFC 5005, or the media:content elements of Media RSS.
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	MediaNS   string     `xml:"xmlns:media,attr,omitempty"`
+	HistoryNS string     `xml:"xmlns:fh,attr,omitempty"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string     `xml:"title"`
+	Link           string     `xml:"link"`
+	Description    string     `xml:"description"`
+	Language       string     `xml:"language,omitempty"`
+	Copyright      string     `xml:"copyright,omitempty"`
+	ManagingEditor string     `xml:"managingEditor,omitempty"`
+	WebMaster      string     `xml:"webMaster,omitempty"`
+	PubDate        string     `xml:"pubDate,omitempty"`
+	LastBuildDate  string     `xml:"lastBuildDate,omitempty"`
+	Generator      string     `xml:"generator,omitempty"`
+	TTL            int        `xml:"ttl,omitempty"`
+	AtomLinks      []atomLink `xml:"atom:link"`
+	Archive        *struct{}  `xml:"fh:archive"`
+	Items          []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string            `xml:"title"`
+	Link        string            `xml:"link"`
+	Description rssCData          `xml:"description"`
+	Categories  []string          `xml:"category"`
+	Enclosure   *rssEnclosure     `xml:"enclosure"`
+	GUID        *rssGUID          `xml:"guid,omitempty"`
+	PubDate     string            `xml:"pubDate,omitempty"`
+	Media       []rssMediaContent `xml:"media:content"`
+}
+
+// rssEnclosure is a media file of an item. RSS allows only one enclosure per
+// item, all media files are given as Media RSS elements.
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssMediaContent struct {
+	URL      string `xml:"url,attr"`
+	FileSize int    `xml:"fileSize,attr,omitempty"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+}
+
+type rssCData struct {
+	Data string `xml:",cdata"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS returns the data as a RSS feed. The parameter feedURL is the URL of the
+// feed without any page selection.
+func (fd *feedData) RSS(feedURL string) *rssFeed {
+	channel := rssChannel{
+		Title:          fd.Title,
+		Link:           fd.Link,
+		Description:    fd.Description,
+		Language:       fd.Language,
+		Copyright:      fd.Copyright,
+		ManagingEditor: fd.ManagingEditor,
+		WebMaster:      fd.WebMaster,
+		LastBuildDate:  rssDate(fd.LastModified()),
+		Generator:      "Zettel Feeds",
+		TTL:            fd.TTL,
+		Items:          make([]*rssItem, 0, len(fd.Items)),
+	}
+	if !fd.Published.IsZero() {
+		channel.PubDate = rssDate(fd.Published)
+	}
+	if feedURL != "" {
+		const typ = "application/rss+xml"
+		channel.AtomLinks = append(channel.AtomLinks, atomLink{Href: fd.Page.URL(feedURL), Rel: "self", Type: typ})
+		for _, pl := range fd.PageLinks() {
+			channel.AtomLinks = append(channel.AtomLinks, atomLink{Href: pl.Page.URL(feedURL), Rel: pl.Rel, Type: typ})
+		}
+		if fd.Hub != "" && fd.Page == currentPage {
+			channel.AtomLinks = append(channel.AtomLinks, atomLink{Href: fd.Hub, Rel: "hub"})
+		}
+	}
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	}
+	if fd.Page.Archive {
+		feed.HistoryNS = historyNamespace
+		feed.Channel.Archive = &struct{}{}
+	}
+	for _, fItem := range fd.Items {
+		item := rssItem{
+			Title:       fItem.Title,
+			Link:        fItem.Link,
+			Description: rssCData{Data: fItem.Content},
+			Categories:  fItem.Tags,
+			GUID:        &rssGUID{IsPermaLink: true, Value: fItem.Link},
+		}
+		if !fItem.Published.IsZero() {
+			item.PubDate = rssDate(fItem.Published)
+		}
+		for i, fe := range fItem.Enclosures {
+			if i == 0 {
+				item.Enclosure = &rssEnclosure{URL: fe.URL, Length: fe.Length, Type: fe.Type}
+			}
+			item.Media = append(item.Media, rssMediaContent{URL: fe.URL, FileSize: fe.Length, Type: fe.Type, Medium: fe.Medium()})
+			feed.MediaNS = "http://search.yahoo.com/mrss/"
+		}
+		feed.Channel.Items = append(feed.Channel.Items, &item)
+	}
+	return &feed
+}
+
+func rssDate(ts time.Time) string { return ts.Format(time.RFC1123Z) }
+
+// Write the RSS feed as XML.
+func (rf *rssFeed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(rf); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,120 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"t73f.de/r/zsc/client"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/domain/meta"
+)
+
+// Keys of the runtime configuration zettel of a Zettelstore.
+const (
+	configKeySiteName         = "site-name"
+	configKeyHomeZettel       = "home-zettel"
+	configKeyDefaultCopyright = "default-copyright"
+	configKeyDefaultLicense   = "default-license"
+	configKeyLang             = meta.KeyLang
+)
+
+// siteInfo contains metadata about a Zettelstore, taken from its runtime
+// configuration zettel and from its home zettel.
+type siteInfo struct {
+	Name        string
+	Language    string
+	Copyright   string
+	License     string
+	HomeZid     id.Zid
+	HomeTitle   string
+	HomeSummary string
+}
+
+// retrieveSite reads the metadata of a Zettelstore. Missing zettel, e.g.
+// because the client is not allowed to read them, are ignored.
+func retrieveSite(ctx context.Context, c *client.Client) siteInfo {
+	var si siteInfo
+	si.HomeZid = id.ZidDefaultHome
+	if mr, err := c.GetMetaData(ctx, id.ZidConfiguration); err != nil {
+		slog.Debug("Unable to read runtime configuration", "upstream", c.Base(), "err", err)
+	} else {
+		m := mr.Meta
+		si.Name = m[configKeySiteName]
+		si.Language = m[configKeyLang]
+		si.Copyright = m[configKeyDefaultCopyright]
+		si.License = m[configKeyDefaultLicense]
+		if zid, errZid := id.Parse(m[configKeyHomeZettel]); errZid == nil && zid.IsValid() {
+			si.HomeZid = zid
+		}
+	}
+	if mr, err := c.GetMetaData(ctx, si.HomeZid); err != nil {
+		slog.Debug("Unable to read home zettel", "upstream", c.Base(), "zid", si.HomeZid, "err", err)
+	} else {
+		si.HomeTitle = mr.Meta[meta.KeyTitle]
+		si.HomeSummary = mr.Meta[meta.KeySummary]
+	}
+	return si
+}
+
+// feedChannel contains the data that describes a feed as a whole.
+type feedChannel struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	Copyright   string
+}
+
+// channel returns the data describing the feed. Values given in the feed
+// definition take precedence over those of the Zettelstore.
+func (fi *feedInfo) channel() feedChannel {
+	var si siteInfo
+	link := fi.URL
+	if up := fi.up; up != nil {
+		si = up.Site()
+		link = up.base.String()
+	}
+	ch := feedChannel{
+		Title:       firstOf(fi.Title, si.Name, si.HomeTitle, fi.name),
+		Link:        firstOf(fi.Link, link),
+		Description: firstOf(fi.Description, si.HomeSummary, si.HomeTitle),
+		Language:    firstOf(fi.Language, si.Language),
+		Copyright:   fi.Copyright,
+	}
+	if fi.filter != "" {
+		ch.Title += " – " + fi.filter
+	}
+	if ch.Copyright == "" {
+		ch.Copyright = si.Copyright
+		if si.License != "" {
+			if ch.Copyright != "" {
+				ch.Copyright += ", "
+			}
+			ch.Copyright += "License: " + si.License
+		}
+	}
+	return ch
+}
+
+// firstOf returns the first non-empty string.
+func firstOf(values ...string) string {
+	for _, val := range values {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
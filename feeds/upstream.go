@@ -0,0 +1,269 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"t73f.de/r/zsc/client"
+)
+
+// Intervals to check an upstream Zettelstore again, and the time a check may
+// take.
+const (
+	revalidateInterval = 5 * time.Minute
+	retryInterval      = 10 * time.Second
+	checkTimeout       = 30 * time.Second
+)
+
+// upstream is a Zettelstore that provides the zettel for one or more feeds.
+//
+// There is one long-lived client for every upstream, which is shared by all
+// feeds of the upstream. All clients use the default HTTP transport, which
+// pools connections. A check creates a new client, which replaces the
+// current one only after it is authenticated. Therefore, a client that was
+// handed out is never changed.
+type upstream struct {
+	base     *url.URL
+	username string
+	password string
+
+	checkMx sync.Mutex // Only one check at a time
+
+	mx      sync.Mutex
+	c       *client.Client
+	version client.VersionInfo
+	site    siteInfo
+	checked time.Time
+	err     error
+
+	media mediaSizes // Sizes of media files, for all feeds
+}
+
+func newUpstream(base *url.URL, username, password string) *upstream {
+	return &upstream{
+		base:     base,
+		username: username,
+		password: password,
+	}
+}
+
+// checkVersion returns an error, if the version of the Zettelstore is not
+// supported.
+func checkVersion(ver client.VersionInfo) error {
+	if ver.Major <= 1 || ver.Major > 2 {
+		return fmt.Errorf("unsupported version: %v", ver)
+	}
+	return nil
+}
+
+// check retrieves the version of the Zettelstore, and authenticates a new
+// client, if credentials are given. Authenticating on every check refreshes
+// the access token before it expires. Metadata about the Zettelstore is
+// read again, so that changes of its configuration are noticed.
+//
+// The network is accessed without holding the lock of the upstream; the
+// caller must hold checkMx. The check is not canceled together with the
+// given context, e.g. if a client of a feed disconnects, because the result
+// is shared by all feeds of the upstream.
+func (up *upstream) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), checkTimeout)
+	defer cancel()
+	c := client.NewClient(up.base)
+	start := time.Now()
+	ver, err := c.GetVersionInfo(ctx)
+	stats.Upstream(up.base.String(), time.Since(start))
+	if err != nil {
+		err = fmt.Errorf("unable to retrieve version: %w", err)
+	} else {
+		err = checkVersion(ver)
+	}
+	if err == nil && up.username != "" {
+		c.SetAuth(up.username, up.password)
+		if errAuth := c.Authenticate(ctx); errAuth != nil {
+			err = fmt.Errorf("unable to authenticate %q: %w", up.username, errAuth)
+		}
+	}
+	var site siteInfo
+	if err == nil {
+		site = retrieveSite(ctx, c)
+	}
+
+	up.mx.Lock()
+	if err == nil {
+		up.c, up.site = c, site
+	}
+	up.version, up.err, up.checked = ver, err, time.Now()
+	up.mx.Unlock()
+	if err != nil {
+		slog.Error("Zettelstore check failed", "upstream", up.base, "err", err)
+	}
+}
+
+// current returns the client of the last check, whether the upstream must be
+// checked again, and the error of the last check.
+func (up *upstream) current() (*client.Client, bool, error) {
+	up.mx.Lock()
+	defer up.mx.Unlock()
+	interval := revalidateInterval
+	if up.err != nil {
+		interval = retryInterval
+	}
+	return up.c, time.Since(up.checked) >= interval, up.err
+}
+
+// Client returns the client of the upstream, and whether it is authenticated.
+// If the last check is too old, the upstream is checked again. While another
+// request checks the upstream, a valid client is returned immediately.
+func (up *upstream) Client(ctx context.Context) (*client.Client, bool, error) {
+	c, stale, err := up.current()
+	if stale {
+		if c != nil && err == nil {
+			if up.checkMx.TryLock() {
+				up.recheck(ctx)
+				up.checkMx.Unlock()
+			}
+		} else {
+			up.checkMx.Lock()
+			up.recheck(ctx)
+			up.checkMx.Unlock()
+		}
+		c, _, err = up.current()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return c, up.username != "", nil
+}
+
+// recheck checks the upstream, if no other check was done in the meantime.
+// The caller must hold checkMx.
+func (up *upstream) recheck(ctx context.Context) {
+	if _, stale, _ := up.current(); stale {
+		up.check(ctx)
+	}
+}
+
+// Site returns the metadata of the Zettelstore, as retrieved by the last
+// successful check.
+func (up *upstream) Site() siteInfo {
+	up.mx.Lock()
+	defer up.mx.Unlock()
+	return up.site
+}
+
+// upstreamStatus is the result of the last check of an upstream.
+type upstreamStatus struct {
+	URL     string    `json:"url"`
+	Version string    `json:"version,omitempty"`
+	Checked time.Time `json:"checked"`
+	Error   string    `json:"error,omitempty"`
+	Feeds   []string  `json:"feeds"`
+}
+
+func (up *upstream) Status() upstreamStatus {
+	up.mx.Lock()
+	defer up.mx.Unlock()
+	st := upstreamStatus{
+		URL:     up.base.String(),
+		Checked: up.checked,
+	}
+	if up.err != nil {
+		st.Error = up.err.Error()
+	} else if !up.checked.IsZero() {
+		st.Version = fmt.Sprintf("%d.%d", up.version.Major, up.version.Minor)
+	}
+	return st
+}
+
+// upstreamRegistry maintains all upstreams that are used by the feeds.
+type upstreamRegistry struct {
+	mx        sync.Mutex
+	upstreams map[string]*upstream
+	feeds     map[string][]string // names of feeds for every upstream
+}
+
+func newUpstreamRegistry() *upstreamRegistry {
+	return &upstreamRegistry{upstreams: map[string]*upstream{}}
+}
+
+// Sync assigns an upstream to every feed. Upstreams that are not used any
+// more are removed, new upstreams are checked immediately.
+func (reg *upstreamRegistry) Sync(ctx context.Context, fs feeds) {
+	reg.mx.Lock()
+	used := make(map[string]*upstream, len(reg.upstreams))
+	feedNames := map[string][]string{}
+	var fresh []*upstream
+	for _, name := range slices.Sorted(maps.Keys(fs)) {
+		fi := fs[name]
+		base, username, password, err := fi.credentials()
+		if err != nil {
+			continue
+		}
+		key := base.String() + " " + username
+		up, found := used[key]
+		if !found {
+			if up, found = reg.upstreams[key]; !found || up.password != password {
+				up = newUpstream(base, username, password)
+				fresh = append(fresh, up)
+			}
+			used[key] = up
+		}
+		fi.up = up
+		feedNames[key] = append(feedNames[key], name)
+	}
+	reg.upstreams, reg.feeds = used, feedNames
+	reg.mx.Unlock()
+
+	var wg sync.WaitGroup
+	for _, up := range fresh {
+		wg.Go(func() {
+			up.checkMx.Lock()
+			up.check(ctx)
+			up.checkMx.Unlock()
+		})
+	}
+	wg.Wait()
+}
+
+// Status returns the status of all upstreams.
+func (reg *upstreamRegistry) Status() []upstreamStatus {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	result := make([]upstreamStatus, 0, len(reg.upstreams))
+	for _, key := range slices.Sorted(maps.Keys(reg.upstreams)) {
+		st := reg.upstreams[key].Status()
+		st.Feeds = reg.feeds[key]
+		result = append(result, st)
+	}
+	return result
+}
+
+func makeStatusHandler(reg *upstreamRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(reg.Status())
+	})
+}
@@ -0,0 +1,497 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2025-present Detlef Stern
+//
+// This file is part of Zettel Feeds.
+//
+// Zettel Feeds is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2025-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"t73f.de/r/zsc/domain/meta"
+	"t73f.de/r/zsc/webapi"
+)
+
+// hubPath is the URL path of the WebSub hub.
+const hubPath = "/hub"
+
+// Limits of the WebSub hub.
+const (
+	defaultLeaseSeconds = 10 * 24 * 60 * 60
+	maxLeaseSeconds     = 30 * 24 * 60 * 60
+	maxSubscriptions    = 1024
+	maxSecretLength     = 200
+	hubRequestTimeout   = 30 * time.Second
+	maxPending          = 64  // Verifications in progress
+	maxPendingPerHost   = 4   // Verifications in progress for a callback host
+	maxDeliveries       = 256 // Distributions waiting for a worker
+	numDeliveryWorkers  = 4
+)
+
+// errNonPublicAddress is returned, if the hub should connect to an address
+// that is not reachable from the internet.
+var errNonPublicAddress = errors.New("non-public address")
+
+// hub is a minimal WebSub hub, as specified by the W3C recommendation
+// "WebSub". It allows subscribers to receive new content of a feed, without
+// polling the feed.
+//
+// Subscriptions are stored in memory only. Since subscribers must renew their
+// subscriptions before the lease expires, they will subscribe again after a
+// restart.
+type hub struct {
+	feeds      *feedSet
+	client     *http.Client
+	deliveries chan *delivery
+
+	mx      sync.Mutex
+	subs    map[string]map[string]*subscription // topic, callback
+	newest  map[string]string                   // feed name, publishing date of newest zettel
+	pending map[string]int                      // callback host, number of verifications
+}
+
+// subscription is a verified subscription for a topic, i.e. a feed URL.
+type subscription struct {
+	topic    string
+	callback string
+	secret   string
+	expires  time.Time
+}
+
+// delivery is the content of a feed that must be sent to a subscriber.
+type delivery struct {
+	sub    *subscription
+	format string
+	data   []byte
+}
+
+func newHub(feeds *feedSet) *hub {
+	return &hub{
+		feeds:      feeds,
+		client:     newHubClient(),
+		deliveries: make(chan *delivery, maxDeliveries),
+		subs:       map[string]map[string]*subscription{},
+		newest:     map[string]string{},
+		pending:    map[string]int{},
+	}
+}
+
+// newHubClient returns the HTTP client to call subscribers. It connects only
+// to public addresses, even after a redirect or if a host name resolves to a
+// private address, so that the hub cannot be used to reach internal services.
+func newHubClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: hubRequestTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if addr, errAddr := netip.ParseAddr(host); errAddr != nil || !isPublicAddr(addr) {
+				return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect to the subscriber without checks
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: hubRequestTimeout, Transport: transport}
+}
+
+// isPublicAddr returns true, if the IP address is reachable from the
+// internet, i.e. it is not a loopback, private, link-local, or multicast
+// address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsUnspecified() && !addr.IsLoopback() && !addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsMulticast()
+}
+
+// checkCallback returns the host of the callback URL of a subscriber, if the
+// URL is valid. Host names are checked when connecting to the subscriber.
+func checkCallback(callback string) (string, bool) {
+	u, err := url.Parse(callback)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return "", false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "", false
+	}
+	if addr, errAddr := netip.ParseAddr(host); errAddr == nil && !isPublicAddr(addr) {
+		return "", false
+	}
+	return host, true
+}
+
+// resolveTopic returns the feed and the format of a topic URL. The topic
+// must be a URL of this server.
+func resolveTopic(ctx context.Context, feeds *feedSet, topic string) (*feedInfo, string, bool) {
+	u, err := url.Parse(topic)
+	if err != nil || !u.IsAbs() || u.RawQuery != "" || !strings.EqualFold(u.Scheme+"://"+u.Host, feeds.base) {
+		return nil, "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return nil, "", false
+	}
+	format := formats[0].name
+	if file := parts[len(parts)-1]; file != "" {
+		format = ""
+		for _, f := range formats {
+			if f.file == file {
+				format = f.name
+				break
+			}
+		}
+		if format == "" {
+			return nil, "", false
+		}
+	}
+	parts = parts[:len(parts)-1]
+	fi, found := feeds.Get(parts[0])
+	if !found {
+		return nil, "", false
+	}
+	switch len(parts) {
+	case 1:
+		return fi, format, true
+	case 3:
+		if parts[1] != filterTag && parts[1] != filterRole {
+			return nil, "", false
+		}
+		ffi, err := fi.Filtered(ctx, parts[1], parts[2])
+		return ffi, format, err == nil
+	}
+	return nil, "", false
+}
+
+// ServeHTTP handles subscription requests.
+func (h *hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	mode := r.PostForm.Get("hub.mode")
+	if mode != "subscribe" && mode != "unsubscribe" {
+		http.Error(w, "Unsupported hub.mode", http.StatusBadRequest)
+		return
+	}
+	callback := r.PostForm.Get("hub.callback")
+	host, ok := checkCallback(callback)
+	if !ok {
+		http.Error(w, "Invalid hub.callback", http.StatusBadRequest)
+		return
+	}
+	topic := r.PostForm.Get("hub.topic")
+	if _, _, found := resolveTopic(r.Context(), h.feeds, topic); !found {
+		http.Error(w, "Unknown hub.topic", http.StatusBadRequest)
+		return
+	}
+	secret := r.PostForm.Get("hub.secret")
+	if len(secret) > maxSecretLength {
+		http.Error(w, "hub.secret too long", http.StatusBadRequest)
+		return
+	}
+	lease := defaultLeaseSeconds
+	if val := r.PostForm.Get("hub.lease_seconds"); val != "" {
+		if iVal, err := strconv.Atoi(val); err == nil && iVal > 0 {
+			lease = min(iVal, maxLeaseSeconds)
+		}
+	}
+	if mode == "subscribe" && !h.canSubscribe(topic, callback) {
+		http.Error(w, "Too many subscriptions", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !h.startVerification(host) {
+		http.Error(w, "Too many pending verifications", http.StatusServiceUnavailable)
+		return
+	}
+
+	sub := &subscription{topic: topic, callback: callback, secret: secret}
+	go func() {
+		defer h.endVerification(host)
+		h.verify(mode, sub, lease)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// startVerification returns true, if another verification may be started
+// for the given callback host.
+func (h *hub) startVerification(host string) bool {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	total := 0
+	for _, n := range h.pending {
+		total += n
+	}
+	if total >= maxPending || h.pending[host] >= maxPendingPerHost {
+		return false
+	}
+	h.pending[host]++
+	return true
+}
+
+func (h *hub) endVerification(host string) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.pending[host]--; h.pending[host] <= 0 {
+		delete(h.pending, host)
+	}
+}
+
+func (h *hub) canSubscribe(topic, callback string) bool {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if _, found := h.subs[topic][callback]; found {
+		return true
+	}
+	count := 0
+	for _, subs := range h.subs {
+		count += len(subs)
+	}
+	return count < maxSubscriptions
+}
+
+// verify asks the subscriber to confirm the intent of a (un-)subscription.
+// If confirmed, the subscription is added or removed.
+func (h *hub) verify(mode string, sub *subscription, lease int) {
+	ctx, cancel := context.WithTimeout(context.Background(), hubRequestTimeout)
+	defer cancel()
+	challenge := rand.Text()
+	u, err := url.Parse(sub.callback)
+	if err != nil {
+		return
+	}
+	q := u.Query()
+	q.Set("hub.mode", mode)
+	q.Set("hub.topic", sub.topic)
+	q.Set("hub.challenge", challenge)
+	if mode == "subscribe" {
+		q.Set("hub.lease_seconds", strconv.Itoa(lease))
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		slog.Info("WebSub verification failed", "callback", sub.callback, "err", err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(challenge)+1)))
+	if err != nil || resp.StatusCode/100 != 2 || strings.TrimSpace(string(body)) != challenge {
+		slog.Info("WebSub verification rejected", "callback", sub.callback, "status", resp.StatusCode)
+		return
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if mode == "unsubscribe" {
+		delete(h.subs[sub.topic], sub.callback)
+		if len(h.subs[sub.topic]) == 0 {
+			delete(h.subs, sub.topic)
+		}
+		slog.Info("WebSub unsubscribed", "topic", sub.topic, "callback", sub.callback)
+		return
+	}
+	sub.expires = time.Now().Add(time.Duration(lease) * time.Second)
+	if h.subs[sub.topic] == nil {
+		h.subs[sub.topic] = map[string]*subscription{}
+	}
+	h.subs[sub.topic][sub.callback] = sub
+	slog.Info("WebSub subscribed", "topic", sub.topic, "callback", sub.callback, "expires", sub.expires)
+}
+
+// subscriptions returns all subscriptions that are not expired, grouped by
+// topic. Expired subscriptions are removed.
+func (h *hub) subscriptions() map[string][]*subscription {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	now := time.Now()
+	result := make(map[string][]*subscription, len(h.subs))
+	for topic, subs := range h.subs {
+		for callback, sub := range subs {
+			if now.After(sub.expires) {
+				delete(subs, callback)
+				continue
+			}
+			result[topic] = append(result[topic], sub)
+		}
+		if len(subs) == 0 {
+			delete(h.subs, topic)
+		}
+	}
+	return result
+}
+
+// Watch checks all subscribed feeds periodically, and distributes their
+// content, if a new zettel was published. Distribution is done by a fixed
+// number of workers, so that slow subscribers do not delay the next check.
+// It returns when the context is done.
+func (h *hub) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for range numDeliveryWorkers {
+		go h.deliver(ctx)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.check(ctx)
+		}
+	}
+}
+
+func (h *hub) check(ctx context.Context) {
+	subs := h.subscriptions()
+	changed := map[string]bool{}
+	for _, topic := range slices.Sorted(maps.Keys(subs)) {
+		fi, format, found := resolveTopic(ctx, h.feeds, topic)
+		if !found {
+			continue
+		}
+		isNew, checked := changed[fi.name]
+		if !checked {
+			var err error
+			if isNew, err = h.isChanged(ctx, fi); err != nil {
+				slog.Warn("Unable to check feed for WebSub", "feed", fi.name, "err", err)
+			}
+			if isNew {
+				fi.invalidate()
+			}
+			changed[fi.name] = isNew
+		}
+		if !isNew {
+			continue
+		}
+		rf, err := fi.render(ctx, currentPage, format)
+		if err != nil {
+			slog.Warn("Unable to render feed for WebSub", "feed", fi.name, "err", err)
+			continue
+		}
+		for _, sub := range subs[topic] {
+			select {
+			case h.deliveries <- &delivery{sub: sub, format: format, data: rf.data}:
+			default:
+				slog.Warn("Too many WebSub distributions, dropped", "topic", topic, "callback", sub.callback)
+			}
+		}
+	}
+}
+
+// isChanged returns true, if the newest published or the newest modified
+// zettel of a feed has changed since the last check. The directives of the
+// feed query are ignored, so that the zettel are ordered by date.
+func (h *hub) isChanged(ctx context.Context, fi *feedInfo) (bool, error) {
+	c, withAuth, err := fi.up.Client(ctx)
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	defer func() { stats.Upstream(fi.up.base.String(), time.Since(start)) }()
+	search := stripDirectives(fi.selectQuery(withAuth))
+	queries := []string{
+		search,
+		restrictQuery(search, meta.KeyModified+searchOperatorExist),
+	}
+	var sb strings.Builder
+	for i, key := range []string{meta.KeyPublished, meta.KeyModified} {
+		query := queries[i] + " " + webapi.OrderDirective + " " + webapi.ReverseDirective + " " +
+			key + " " + webapi.LimitDirective + " 1"
+		_, _, ml, errQuery := c.QueryZettelData(ctx, query)
+		if errQuery != nil {
+			return false, errQuery
+		}
+		if len(ml) > 0 {
+			fmt.Fprintf(&sb, "%s %s %s\n", ml[0].ID, key, ml[0].Meta[key])
+		}
+	}
+	newest := sb.String()
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	prev, found := h.newest[fi.name]
+	h.newest[fi.name] = newest
+	return found && prev != newest, nil
+}
+
+// deliver distributes feed content until the context is done.
+func (h *hub) deliver(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-h.deliveries:
+			h.distribute(ctx, d.sub, d.format, d.data)
+		}
+	}
+}
+
+// distribute sends the content of a feed to a subscriber.
+func (h *hub) distribute(ctx context.Context, sub *subscription, format string, data []byte) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.callback, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", contentType(format))
+	req.Header.Set("Link", fmt.Sprintf(`<%s>; rel="hub", <%s>; rel="self"`, h.feeds.hub, sub.topic))
+	if sub.secret != "" {
+		mac := hmac.New(sha256.New, []byte(sub.secret))
+		_, _ = mac.Write(data)
+		req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		slog.Info("WebSub distribution failed", "callback", sub.callback, "err", err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		h.mx.Lock()
+		delete(h.subs[sub.topic], sub.callback)
+		h.mx.Unlock()
+		slog.Info("WebSub subscription gone", "topic", sub.topic, "callback", sub.callback)
+	case resp.StatusCode/100 != 2:
+		slog.Info("WebSub distribution rejected", "callback", sub.callback, "status", resp.StatusCode)
+	}
+}
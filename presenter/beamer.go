@@ -0,0 +1,525 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/domain/meta"
+	"t73f.de/r/zsc/sz"
+	"t73f.de/r/zsx"
+)
+
+// beamerImageSyntax lists the image syntax values that pdfLaTeX is able to
+// include.
+var beamerImageSyntax = map[string]bool{
+	"jpeg": true,
+	"jpg":  true,
+	"pdf":  true,
+	"png":  true,
+}
+
+// beamerLanguage maps a language to the name of its babel option.
+var beamerLanguage = map[string]string{
+	"de": "ngerman",
+	"en": "english",
+	"fr": "french",
+	"it": "italian",
+	"es": "spanish",
+}
+
+// beamerRenderer creates a LaTeX document for the Beamer class. Images are
+// referenced as files with the names of the static export. If archive is set,
+// the document is delivered together with all images as a zip file.
+type beamerRenderer struct {
+	cfg     *slidesConfig
+	archive bool
+}
+
+func (*beamerRenderer) Role() string            { return SlideRoleShow }
+func (*beamerRenderer) Prepare(context.Context) {}
+func (br *beamerRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
+	tg := texGenerator{sb: &strings.Builder{}}
+	tg.writeDocument(slides, author)
+	if !br.archive {
+		w.Header().Set("Content-Type", "application/x-tex; charset=utf-8")
+		_, _ = io.WriteString(w, tg.sb.String())
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/zip")
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", slides.zid.String()+".zip"))
+	target := &zipTarget{zw: zip.NewWriter(w)}
+	err := target.WriteFile(exportBeamerFile, []byte(tg.sb.String()))
+	if err == nil {
+		err = exportImages(slides, target)
+	}
+	if err = errors.Join(err, target.Close()); err != nil {
+		slog.Error("Unable to write Beamer archive", "zid", slides.zid, "err", err)
+	}
+}
+
+// texGenerator transforms the slides into LaTeX.
+type texGenerator struct {
+	sb       *strings.Builder
+	curSlide *slideInfo
+	fragile  bool // Current frame contains verbatim text
+	inArg    int  // Depth of macro arguments, which must not contain verbatim text
+}
+
+func (tg *texGenerator) writeDocument(slides *slideSet, author string) {
+	tg.sb.WriteString("\\documentclass{beamer}\n" +
+		"\\usepackage[utf8]{inputenc}\n" +
+		"\\usepackage[T1]{fontenc}\n")
+	if lang, found := beamerLanguage[slides.Lang()]; found {
+		_, _ = fmt.Fprintf(tg.sb, "\\usepackage[%s]{babel}\n", lang)
+	}
+	tg.sb.WriteString("\\usepackage{graphicx}\n" +
+		"\\usepackage[normalem]{ulem}\n")
+
+	offset := 1
+	title := slides.Title()
+	if title != nil {
+		offset++
+		tg.sb.WriteString("\\title{")
+		tg.inlines(title)
+		tg.sb.WriteString("}\n")
+		if subtitle := slides.Subtitle(); subtitle != nil {
+			tg.sb.WriteString("\\subtitle{")
+			tg.inlines(subtitle)
+			tg.sb.WriteString("}\n")
+		}
+		_, _ = fmt.Fprintf(tg.sb, "\\author{%s}\n", texEscape(author))
+		if ts := slides.GetPublished(); ts.After(time.Time{}) {
+			_, _ = fmt.Fprintf(tg.sb, "\\date{%s}\n", ts.Format("2006-01-02"))
+		} else {
+			tg.sb.WriteString("\\date{}\n")
+		}
+	}
+
+	tg.sb.WriteString("\n\\begin{document}\n")
+	if title != nil {
+		tg.sb.WriteString("\n\\begin{frame}[label=slide1]\n\\titlepage\n\\end{frame}\n")
+	}
+	for si := slides.Slides(SlideRoleShow, offset); si != nil; si = si.Next() {
+		tg.curSlide = si
+		number := si.Number
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			tg.writeFrame(sub, number)
+			number = 0
+		}
+	}
+	tg.sb.WriteString("\n\\end{document}\n")
+}
+
+// writeFrame writes a main slide or a sub-slide as a frame. Only the frame of
+// a main slide gets a label, so that links to the slide are possible.
+func (tg *texGenerator) writeFrame(si *slideInfo, number int) {
+	outer := tg.sb
+	tg.sb = &strings.Builder{}
+	tg.fragile = false
+	if title := si.Slide.title; title != nil {
+		tg.sb.WriteString("\\frametitle{")
+		tg.inlines(title)
+		tg.sb.WriteString("}\n")
+	}
+	tg.blocks(si.Slide.content)
+	body := tg.sb.String()
+	tg.sb = outer
+
+	var options []string
+	if tg.fragile {
+		options = append(options, "fragile")
+	}
+	if number > 0 {
+		options = append(options, fmt.Sprintf("label=slide%d", number))
+	}
+	tg.sb.WriteString("\n\\begin{frame}")
+	if len(options) > 0 {
+		_, _ = fmt.Fprintf(tg.sb, "[%s]", strings.Join(options, ","))
+	}
+	tg.sb.WriteByte('\n')
+	tg.sb.WriteString(body)
+	tg.sb.WriteString("\\end{frame}\n")
+}
+
+// blocks writes all block nodes of a list. The list may start with the
+// symbol BLOCK.
+func (tg *texGenerator) blocks(lst *sx.Pair) {
+	for obj := range lst.Values() {
+		if sym, args := getSymbolNode(obj); sym != nil {
+			if sym.IsEqualSymbol(zsx.SymBlock) {
+				tg.blocks(args)
+			} else {
+				tg.block(sym, args)
+			}
+		} else if isBlockList(obj) {
+			inner, _ := sx.GetPair(obj)
+			tg.blocks(inner)
+		}
+	}
+}
+
+func (tg *texGenerator) block(sym *sx.Symbol, args *sx.Pair) {
+	switch {
+	case sym.IsEqualSymbol(zsx.SymPara):
+		tg.inlines(args)
+		tg.sb.WriteString("\n\n")
+	case sym.IsEqualSymbol(zsx.SymHeading):
+		tg.heading(args)
+	case sym.IsEqualSymbol(zsx.SymThematic):
+		if !zsx.GetAttributes(args.Car()).HasDefault() {
+			tg.sb.WriteString("\\noindent\\rule{\\linewidth}{0.4pt}\n\n")
+		}
+	case sym.IsEqualSymbol(zsx.SymListOrdered):
+		tg.list("enumerate", args)
+	case sym.IsEqualSymbol(zsx.SymListUnordered):
+		tg.list("itemize", args)
+	case sym.IsEqualSymbol(zsx.SymListQuote):
+		tg.sb.WriteString("\\begin{quote}\n")
+		for item := range args.Values() {
+			if lst, isPair := sx.GetPair(item); isPair && isBlockList(item) {
+				tg.blocks(lst)
+			}
+		}
+		tg.sb.WriteString("\\end{quote}\n")
+	case sym.IsEqualSymbol(zsx.SymDescription):
+		tg.description(args)
+	case sym.IsEqualSymbol(zsx.SymTable):
+		tg.table(args)
+	case sym.IsEqualSymbol(zsx.SymVerbatimCode), sym.IsEqualSymbol(zsx.SymVerbatimEval),
+		sym.IsEqualSymbol(zsx.SymVerbatimZettel):
+		tg.verbatim(getFirstString(args))
+	case sym.IsEqualSymbol(zsx.SymVerbatimMath):
+		_, _ = fmt.Fprintf(tg.sb, "\\[\n%s\n\\]\n\n", getFirstString(args))
+	case sym.IsEqualSymbol(zsx.SymRegionBlock):
+		tg.region(args)
+	case sym.IsEqualSymbol(zsx.SymRegionQuote):
+		tg.sb.WriteString("\\begin{quote}\n")
+		tg.regionContent(args)
+		if cite := args.Tail().Tail(); cite != nil {
+			tg.sb.WriteString("\\hfill---")
+			tg.inlines(cite)
+			tg.sb.WriteByte('\n')
+		}
+		tg.sb.WriteString("\\end{quote}\n")
+	case sym.IsEqualSymbol(zsx.SymRegionVerse):
+		tg.sb.WriteString("\\begin{verse}\n")
+		tg.regionContent(args)
+		tg.sb.WriteString("\\end{verse}\n")
+	}
+}
+
+func (tg *texGenerator) heading(args *sx.Pair) {
+	num, isNumber := sx.GetNumber(args.Car())
+	if !isNumber {
+		return
+	}
+	if level, _ := num.(sx.Int64); level == 1 && zsx.GetAttributes(args.Tail().Car()).HasDefault() {
+		return
+	}
+	tg.sb.WriteString("\\structure{\\textbf{")
+	tg.inlines(args.Tail())
+	tg.sb.WriteString("}}\n\n")
+}
+
+func (tg *texGenerator) list(env string, args *sx.Pair) {
+	_, _ = fmt.Fprintf(tg.sb, "\\begin{%s}\n", env)
+	for item := range args.Values() {
+		if lst, isPair := sx.GetPair(item); isPair && isBlockList(item) {
+			tg.sb.WriteString("\\item ")
+			tg.blocks(lst)
+		}
+	}
+	_, _ = fmt.Fprintf(tg.sb, "\\end{%s}\n", env)
+}
+
+func (tg *texGenerator) description(args *sx.Pair) {
+	tg.sb.WriteString("\\begin{description}\n")
+	for obj := range args.Values() {
+		if sym, termArgs := getSymbolNode(obj); sym != nil && sym.IsEqualSymbol(zsx.SymInline) {
+			tg.sb.WriteString("\\item[{")
+			tg.inlines(termArgs)
+			tg.sb.WriteString("}] ")
+			continue
+		}
+		if lst, isPair := sx.GetPair(obj); isPair && isBlockList(obj) {
+			tg.blocks(lst)
+		}
+	}
+	tg.sb.WriteString("\\end{description}\n")
+}
+
+// table writes a table. The first row is the header.
+func (tg *texGenerator) table(args *sx.Pair) {
+	var rows [][]*sx.Pair
+	header := true
+	for obj := range args.Values() {
+		lst, isPair := sx.GetPair(obj)
+		if !isPair {
+			continue
+		}
+		var row []*sx.Pair
+		for cell := range lst.Values() {
+			if sym, cellArgs := getSymbolNode(cell); sym != nil && sym.IsEqualSymbol(zsx.SymCell) {
+				row = append(row, cellArgs)
+			}
+		}
+		if len(row) == 0 {
+			if len(rows) == 0 && lst == nil {
+				header = false
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(tg.sb, "\\begin{tabular}{%s}\n", strings.Repeat("l", cols))
+	for rowNo, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				tg.sb.WriteString(" & ")
+			}
+			if header && rowNo == 0 {
+				tg.sb.WriteString("\\textbf{")
+				tg.inlines(cell)
+				tg.sb.WriteByte('}')
+			} else {
+				tg.inlines(cell)
+			}
+		}
+		tg.sb.WriteString(" \\\\\n")
+		if header && rowNo == 0 {
+			tg.sb.WriteString("\\hline\n")
+		}
+	}
+	tg.sb.WriteString("\\end{tabular}\n\n")
+}
+
+// verbatim writes a code block. Within the argument of a macro, like \note or
+// \footnote, the verbatim environment is not allowed; there, every line is
+// written in a typewriter font instead.
+func (tg *texGenerator) verbatim(s string) {
+	if tg.inArg > 0 {
+		tg.sb.WriteString("{\\ttfamily\n")
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				tg.sb.WriteString("\\\\\n")
+			}
+			tg.sb.WriteString("\\mbox{}")
+			tg.sb.WriteString(strings.ReplaceAll(texEscape(line), " ", "~"))
+		}
+		tg.sb.WriteString("\\par}\n")
+		return
+	}
+	tg.fragile = true
+	tg.sb.WriteString("\\begin{verbatim}\n")
+	tg.sb.WriteString(strings.ReplaceAll(s, "\\end{verbatim}", "\\end {verbatim}"))
+	tg.sb.WriteString("\n\\end{verbatim}\n")
+}
+
+// region writes the content of a region, according to the semantics of the
+// HTML generator for the slide show: speaker notes become notes of Beamer,
+// content for the handout is omitted.
+func (tg *texGenerator) region(args *sx.Pair) {
+	if val, found := zsx.GetAttributes(args.Car()).Get(""); found {
+		switch val {
+		case "show", "show-note", "both", "note":
+			tg.sb.WriteString("\\note{")
+			tg.inArg++
+			tg.regionContent(args)
+			tg.inArg--
+			tg.sb.WriteString("}\n")
+			return
+		case "handout", "handout-note", "only-handout":
+			return
+		}
+	}
+	tg.regionContent(args)
+}
+
+func (tg *texGenerator) regionContent(args *sx.Pair) {
+	if lst, isPair := sx.GetPair(args.Tail().Car()); isPair {
+		tg.blocks(lst)
+	}
+}
+
+// inlines writes all inline nodes of a list.
+func (tg *texGenerator) inlines(lst *sx.Pair) {
+	for obj := range lst.Values() {
+		sym, args := getSymbolNode(obj)
+		if sym == nil {
+			continue
+		}
+		switch {
+		case sym.IsEqualSymbol(zsx.SymText):
+			if s, isString := sx.GetString(args.Car()); isString {
+				tg.sb.WriteString(texEscape(s.GetValue()))
+			}
+		case sym.IsEqualSymbol(zsx.SymSoft):
+			tg.sb.WriteByte('\n')
+		case sym.IsEqualSymbol(zsx.SymHard):
+			tg.sb.WriteString("\\\\\n")
+		case sym.IsEqualSymbol(zsx.SymLink):
+			tg.link(args)
+		case sym.IsEqualSymbol(zsx.SymInline), sym.IsEqualSymbol(zsx.SymCite),
+			sym.IsEqualSymbol(zsx.SymMark), sym.IsEqualSymbol(zsx.SymFormatSpan):
+			tg.inlines(args)
+		case sym.IsEqualSymbol(zsx.SymFormatEmph):
+			tg.command("emph", args)
+		case sym.IsEqualSymbol(zsx.SymFormatStrong):
+			tg.command("textbf", args)
+		case sym.IsEqualSymbol(zsx.SymFormatInsert):
+			tg.command("uline", args)
+		case sym.IsEqualSymbol(zsx.SymFormatDelete):
+			tg.command("sout", args)
+		case sym.IsEqualSymbol(zsx.SymFormatSuper):
+			tg.command("textsuperscript", args)
+		case sym.IsEqualSymbol(zsx.SymFormatSub):
+			tg.command("textsubscript", args)
+		case sym.IsEqualSymbol(zsx.SymFormatMark):
+			tg.command("alert", args)
+		case sym.IsEqualSymbol(zsx.SymFormatQuote):
+			tg.sb.WriteString("``")
+			tg.inlines(args)
+			tg.sb.WriteString("''")
+		case sym.IsEqualSymbol(zsx.SymLiteralCode), sym.IsEqualSymbol(zsx.SymLiteralInput),
+			sym.IsEqualSymbol(zsx.SymLiteralOutput):
+			_, _ = fmt.Fprintf(tg.sb, "\\texttt{%s}", texEscape(getFirstString(args)))
+		case sym.IsEqualSymbol(zsx.SymLiteralMath):
+			_, _ = fmt.Fprintf(tg.sb, "$%s$", getFirstString(args))
+		case sym.IsEqualSymbol(zsx.SymEndnote):
+			tg.command("footnote", args)
+		case sym.IsEqualSymbol(zsx.SymEmbed):
+			tg.embed(args)
+		}
+	}
+}
+
+func (tg *texGenerator) command(name string, args *sx.Pair) {
+	_, _ = fmt.Fprintf(tg.sb, "\\%s{", name)
+	tg.inArg++
+	tg.inlines(args)
+	tg.inArg--
+	tg.sb.WriteByte('}')
+}
+
+// link writes a link. Links to other slides point to their frame, external
+// links to their URL. All other links are shown as text only.
+func (tg *texGenerator) link(args *sx.Pair) {
+	refSym, ref := zsx.GetReference(args.Tail())
+	text := args.Tail().Tail()
+	writeText := func() {
+		if text == nil {
+			tg.sb.WriteString(texEscape(ref))
+		} else {
+			tg.inlines(text)
+		}
+	}
+	switch {
+	case sz.SymRefStateZettel.IsEqual(refSym):
+		strZid, _, _ := strings.Cut(ref, "#")
+		zid, err := id.Parse(strZid)
+		if si := tg.curSlide.FindSlide(zid); err == nil && si != nil {
+			_, _ = fmt.Fprintf(tg.sb, "\\hyperlink{slide%d}{", si.Number)
+			writeText()
+			tg.sb.WriteByte('}')
+			return
+		}
+	case zsx.SymRefStateExternal.IsEqual(refSym):
+		_, _ = fmt.Fprintf(tg.sb, "\\href{%s}{", texEscapeURL(ref))
+		writeText()
+		tg.sb.WriteByte('}')
+		return
+	}
+	writeText()
+}
+
+// embed writes an image, which is stored as a file of the static export. If
+// the image cannot be included by pdfLaTeX, its description is shown
+// instead.
+func (tg *texGenerator) embed(args *sx.Pair) {
+	_, ref := zsx.GetReference(args.Tail())
+	syntax := getFirstString(args.Tail().Tail())
+	if zid, err := id.Parse(ref); err == nil && beamerImageSyntax[syntax] {
+		_, _ = fmt.Fprintf(tg.sb, "\\includegraphics[width=\\linewidth,height=0.7\\textheight,keepaspectratio]{%s}",
+			exportImageFile(zid.String(), syntax))
+		return
+	}
+	tg.sb.WriteByte('[')
+	if text := args.Tail().Tail().Tail(); text != nil {
+		tg.inlines(text)
+	} else if syntax == meta.ValueSyntaxSVG {
+		tg.sb.WriteString("SVG image " + texEscape(ref))
+	} else {
+		tg.sb.WriteString("Image " + texEscape(ref))
+	}
+	tg.sb.WriteByte(']')
+}
+
+// texEscape escapes all characters that have a special meaning in LaTeX.
+func texEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString("\\textbackslash{}")
+		case '{', '}', '$', '&', '#', '_', '%':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '~':
+			sb.WriteString("\\textasciitilde{}")
+		case '^':
+			sb.WriteString("\\textasciicircum{}")
+		case '<':
+			sb.WriteString("\\textless{}")
+		case '>':
+			sb.WriteString("\\textgreater{}")
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// texEscapeURL escapes the characters of an URL that have a special meaning
+// within the argument of \href.
+func texEscapeURL(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '#', '%', '{', '}':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
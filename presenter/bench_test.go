@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+// benchSizes are the number of slides of the benchmarked slide sets.
+var benchSizes = []int{10, 100, 500}
+
+// benchStore returns a fake Zettelstore with a slide set of n slides, taken
+// from the "10000" data set. Every tenth slide links to an additional zettel.
+func benchStore(b *testing.B, n int) *zstest.Server {
+	b.Helper()
+	zs, err := zstest.LoadMarkdown("../10000/files.md", n+n/10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	slides, additional := zs[:n], zs[n:]
+	var sb strings.Builder
+	for i, z := range slides {
+		z.Meta["role"] = "slide"
+		if i%10 == 0 && len(additional) > 0 {
+			z.Content += fmt.Sprintf("\n\nSee [more](%s).\n", additional[i/10].ID)
+		}
+		_, _ = fmt.Fprintf(&sb, "* [[%s]]\n", z.ID)
+	}
+	for _, z := range additional {
+		z.Meta["visibility"] = "public"
+	}
+	return zstest.NewServer(append(zs, zstest.NewZettel(zidTestSlideSet, sb.String(),
+		"title", "Benchmark",
+		"role", "slideset",
+		"author", "Ada",
+	))...)
+}
+
+func BenchmarkSetupSlideSet(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("slides=%d", n), func(b *testing.B) {
+			store := benchStore(b, n)
+			cfg, _ := startPresenter(b, store)
+			store.ResetRequests()
+			b.ReportAllocs()
+			for b.Loop() {
+				loadSlideSet(b, cfg, zidTestSlideSet)
+			}
+			store.ReportRequests(b)
+		})
+	}
+}
+
+func BenchmarkProcessSlideSet(b *testing.B) {
+	for _, suffix := range []string{"reveal", "html"} {
+		for _, n := range benchSizes {
+			b.Run(fmt.Sprintf("%s/slides=%d", suffix, n), func(b *testing.B) {
+				store := benchStore(b, n)
+				cfg, _ := startPresenter(b, store)
+				handler := makeHandler(cfg)
+				path := "/" + zidTestSlideSet + "." + suffix
+				store.ResetRequests()
+				b.ReportAllocs()
+				for b.Loop() {
+					rec := httptest.NewRecorder()
+					handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+					if rec.Code != http.StatusOK {
+						b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+					}
+				}
+				store.ReportRequests(b)
+			})
+		}
+	}
+}
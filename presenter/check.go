@@ -0,0 +1,245 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sxwebs/sxhtml"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/shtml"
+	"t73f.de/r/zsc/text"
+)
+
+// Severity of a finding.
+const (
+	severityError   = "error"   // Slide set cannot be shown as intended
+	severityWarning = "warning" // Probably unintended
+	severityInfo    = "info"    // Allowed, but worth noting
+)
+
+// Kinds of findings.
+const (
+	findingMissing     = "missing"      // Zettel does not exist
+	findingUnavailable = "unavailable"  // Zettel or image cannot be retrieved
+	findingNonPublic   = "non-public"   // Linked zettel is not shown
+	findingImageSyntax = "image-syntax" // Image cannot be embedded in the handout
+	findingSlideRole   = "slide-role"   // Slide is neither shown nor in handout
+	findingDuplicate   = "duplicate"    // Slide occurs more than once
+	findingEmptySlide  = "empty-slide"  // Sub-slide without content
+)
+
+// embeddableImageSyntax lists the image syntax values that are embedded into
+// the handout.
+var embeddableImageSyntax = map[string]bool{
+	"gif":  true,
+	"jpeg": true,
+	"jpg":  true,
+	"png":  true,
+	"svg":  true,
+}
+
+// checkFinding is a problem of a slide set.
+type checkFinding struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Zid      string `json:"zid"`
+	Message  string `json:"message"`
+}
+
+// checkReport is the result of checking a slide set.
+type checkReport struct {
+	Zid      string          `json:"zid"`
+	Title    string          `json:"title"`
+	Slides   int             `json:"slides"`
+	Findings []*checkFinding `json:"findings"`
+}
+
+func (cr *checkReport) add(severity, kind string, zid id.Zid, format string, args ...any) {
+	cr.Findings = append(cr.Findings, &checkFinding{
+		Severity: severity,
+		Kind:     kind,
+		Zid:      zid.String(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// HasErrors returns true, if the report contains at least one error.
+func (cr *checkReport) HasErrors() bool {
+	for _, f := range cr.Findings {
+		if f.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSlideSet checks a retrieved slide set.
+func checkSlideSet(slides *slideSet) *checkReport {
+	cr := &checkReport{
+		Zid:      slides.zid.String(),
+		Title:    text.EvaluateInlineString(slides.Title()),
+		Slides:   len(slides.seqSlide),
+		Findings: []*checkFinding{},
+	}
+	for _, w := range slides.Warnings() {
+		switch {
+		case w.errMsg == errMsgNotFound:
+			cr.add(severityError, findingMissing, w.zid, "Zettel %s does not exist", w.zid)
+		case w.errMsg == errMsgForbidden && slides.GetSlide(w.zid) == nil:
+			cr.add(severityWarning, findingNonPublic, w.zid, "Zettel %s is linked, but not readable; it will not appear in the handout", w.zid)
+		default:
+			cr.add(severityError, findingUnavailable, w.zid, "Zettel %s cannot be shown: %s", w.zid, w.errMsg)
+		}
+	}
+	for _, zid := range slides.NonPublic() {
+		cr.add(severityWarning, findingNonPublic, zid, "Zettel %s is linked, but not public; it will not appear in the handout", zid)
+	}
+	images := slides.Images()
+	slices.Sort(images)
+	for _, zid := range images {
+		if img, found := slides.GetImage(zid); found && !embeddableImageSyntax[img.syntax] {
+			cr.add(severityWarning, findingImageSyntax, zid, "Image %s has syntax %q, which cannot be embedded into the handout", zid, img.syntax)
+		}
+	}
+
+	count := make(map[id.Zid]int, len(slides.seqSlide))
+	for _, sl := range slides.seqSlide {
+		count[sl.zid]++
+		if count[sl.zid] > 1 || sl.errMsg != "" {
+			continue
+		}
+		if sl.role != "" && sl.role != SlideRoleShow && sl.role != SlideRoleHandout {
+			cr.add(severityWarning, findingSlideRole, sl.zid, "Slide %s has slide-role %q, it is neither shown nor part of the handout", sl.zid, sl.role)
+		}
+		si := slideInfo{Slide: sl}
+		si.SplitChildren()
+		n := 0
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			n++
+			if sub.Slide.content.Length() == 0 {
+				cr.add(severityWarning, findingEmptySlide, sl.zid, "Sub-slide %d of slide %s is empty", n, sl.zid)
+			}
+		}
+	}
+	for _, zid := range slides.SlideZids() {
+		if n := count[zid]; n > 1 {
+			cr.add(severityInfo, findingDuplicate, zid, "Slide %s occurs %d times", zid, n)
+			count[zid] = 0
+		}
+	}
+	return cr
+}
+
+// processCheck checks a slide set and reports the result as HTML, or as JSON
+// if requested by the query parameter "enc=json".
+func processCheck(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid id.Zid) {
+	slides, err := retrieveSlideSet(r.Context(), cfg.c, zid)
+	if err != nil {
+		reportRetrieveError(w, zid, err, "slide set")
+		return
+	}
+	cr := checkSlideSet(slides)
+	if r.URL.Query().Get("enc") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = cr.WriteJSON(w)
+		return
+	}
+	renderCheckReport(w, slides, cr)
+}
+
+// WriteJSON writes the report in JSON format.
+func (cr *checkReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cr)
+}
+
+// WriteText writes the report in a human readable format, one finding per
+// line.
+func (cr *checkReport) WriteText(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Slide set %s %q: %d slides, %d findings\n", cr.Zid, cr.Title, cr.Slides, len(cr.Findings))
+	for _, f := range cr.Findings {
+		_, _ = fmt.Fprintf(w, "%s: %s: %s\n", f.Severity, f.Kind, f.Message)
+	}
+}
+
+func renderCheckReport(w http.ResponseWriter, slides *slideSet, cr *checkReport) {
+	gen := newGenerator(nil, langDE, nil, false, false)
+	title := "Check: " + cr.Title
+
+	headHTML := getHTMLHead()
+	headHTML.LastPair().
+		AppendBang(sx.MakeList(shtml.SymTitle, sx.MakeString(title))).
+		AppendBang(getPrefixedCSS(""))
+
+	headerHTML := sx.MakeList(
+		sxhtml.MakeSymbol("header"),
+		sx.MakeList(shtml.SymH1, sx.MakeString(title)),
+		sx.MakeList(shtml.SymP, sx.MakeString(fmt.Sprintf("%d slides, %d findings", cr.Slides, len(cr.Findings)))),
+	)
+	bodyHTML := sx.MakeList(shtml.SymBody, headerHTML)
+	if len(cr.Findings) > 0 {
+		lstFindings := sx.MakeList(shtml.SymUL)
+		curr := lstFindings
+		for _, f := range cr.Findings {
+			curr = curr.AppendBang(sx.MakeList(
+				shtml.SymLI,
+				getClassAttr("check-"+f.Severity),
+				sx.MakeList(sxhtml.MakeSymbol("strong"), sx.MakeString(f.Severity+": ")),
+				getSimpleLink("/"+f.Zid, sx.MakeList(sx.MakeString(f.Zid))),
+				sx.MakeString(" "+f.Message),
+			))
+		}
+		bodyHTML.LastPair().AppendBang(lstFindings)
+	}
+	bodyHTML.LastPair().AppendBang(sx.MakeList(
+		shtml.SymP,
+		getSimpleLink("/"+slides.zid.String(), sx.MakeList(sx.MakeString("Slides"))),
+		sx.MakeString(", "),
+		getSimpleLink("/"+slides.zid.String()+".check?enc=json", sx.MakeList(sx.MakeString("JSON"))),
+	))
+	gen.writeHTMLDocument(w, slides.Lang(), headHTML, bodyHTML)
+}
+
+// runCheck checks a slide set from the command line. It returns the exit code
+// of the program: 0 if there are no errors, 1 if there are errors, and 2 if
+// the slide set could not be retrieved.
+func runCheck(args []string) int {
+	cmd := newCommand("check")
+	asJSON := cmd.fs.Bool("json", false, "Write the report in JSON format")
+	zid, ok := cmd.parse(args)
+	if !ok {
+		return 2
+	}
+	return cmd.run(zid, func(_ context.Context, _ *slidesConfig, slides *slideSet) int {
+		cr := checkSlideSet(slides)
+		if *asJSON {
+			_ = cr.WriteJSON(os.Stdout)
+		} else {
+			cr.WriteText(os.Stdout)
+		}
+		if cr.HasErrors() {
+			return 1
+		}
+		return 0
+	})
+}
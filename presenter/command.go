@@ -0,0 +1,98 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"t73f.de/r/zsc/domain/id"
+	"zettelstore.de/contrib/server"
+)
+
+// command contains the flags and the setup that the subcommands check,
+// export, and pdf have in common. Additional flags are defined on fs.
+type command struct {
+	fs       *flag.FlagSet
+	timeout  *time.Duration
+	logLevel *string
+}
+
+func newCommand(name string) *command {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage of %s %s:\n", os.Args[0], name)
+		fs.PrintDefaults()
+		_, _ = io.WriteString(out, "  ZID [URL] Zettel identifier of slide set, URL of Zettelstore (default: \"http://127.0.0.1:23123\")\n")
+	}
+	return &command{
+		fs:       fs,
+		timeout:  fs.Duration("t", 30*time.Second, "Timeout for retrieving data from Zettelstore"),
+		logLevel: fs.String("log-level", "error", "Log level: debug, info, warn, error"),
+	}
+}
+
+// parse parses the command line arguments and returns the zettel identifier
+// of the slide set.
+func (cmd *command) parse(args []string) (id.Zid, bool) {
+	_ = cmd.fs.Parse(args)
+	zid, err := id.Parse(cmd.fs.Arg(0))
+	if err != nil {
+		cmd.fs.Usage()
+		return id.Invalid, false
+	}
+	return zid, true
+}
+
+// run retrieves the slide set and calls fn with it. It returns the exit code
+// of fn, or 2 if the slide set could not be retrieved. The timeout starts
+// after connecting to the Zettelstore, because the user may be asked for
+// name and password.
+func (cmd *command) run(zid id.Zid, fn func(context.Context, *slidesConfig, *slideSet) int) int {
+	if err := server.SetupLogging(*cmd.logLevel, "text"); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to configure logging: %v\n", err)
+		return 2
+	}
+	c, err := getClient(context.Background(), cmd.fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to zettelstore: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *cmd.timeout)
+	defer cancel()
+	cfg, err := getConfig(ctx, c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve presenter config: %v\n", err)
+		return 2
+	}
+	slides, err := retrieveSlideSet(ctx, c, zid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve slide set %s: %v\n", zid, err)
+		return 2
+	}
+	return fn(ctx, &cfg, slides)
+}
+
+// printWarnings writes all zettel of the slide set that could not be shown.
+func printWarnings(slides *slideSet) {
+	for _, w := range slides.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: zettel %s %s\n", w.zid, w.errMsg)
+	}
+}
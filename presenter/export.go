@@ -0,0 +1,269 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sxwebs/sxhtml"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/shtml"
+	"t73f.de/r/zsc/text"
+)
+
+// File names of a static export.
+const (
+	exportIndexFile   = "index.html"   // Slide show
+	exportHandoutFile = "handout.html" // Handout
+	exportBeamerFile  = "slides.tex"   // LaTeX Beamer
+)
+
+// exportZettelFile returns the file name of an additional zettel within a
+// static export.
+func exportZettelFile(zid id.Zid) string { return zid.String() + ".html" }
+
+// exportImageFile returns the file name of an image within a static export.
+func exportImageFile(strZid, syntax string) string { return strZid + "." + syntax }
+
+// exportTarget stores the files of a static export.
+type exportTarget interface {
+	WriteFile(name string, data []byte) error
+	Close() error
+}
+
+// newExportTarget returns a zip file target, if the path ends with ".zip".
+// Otherwise the path names a directory.
+func newExportTarget(path string) (exportTarget, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return &zipTarget{f: f, zw: zip.NewWriter(f)}, nil
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirTarget{dir: path}, nil
+}
+
+type dirTarget struct{ dir string }
+
+func (dt *dirTarget) WriteFile(name string, data []byte) error {
+	path := filepath.Join(dt.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+func (*dirTarget) Close() error { return nil }
+
+// zipTarget writes the files into a zip archive. If f is not nil, it is
+// closed together with the archive.
+type zipTarget struct {
+	f  io.Closer
+	zw *zip.Writer
+}
+
+func (zt *zipTarget) WriteFile(name string, data []byte) error {
+	w, err := zt.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+func (zt *zipTarget) Close() error {
+	err := zt.zw.Close()
+	if zt.f != nil {
+		err = errors.Join(err, zt.f.Close())
+	}
+	return err
+}
+
+// pageBuffer stores a rendered page. It allows to use a renderer, which writes
+// to a http.ResponseWriter, for a static export.
+type pageBuffer struct {
+	bytes.Buffer
+	header http.Header
+}
+
+func (pb *pageBuffer) Header() http.Header {
+	if pb.header == nil {
+		pb.header = make(http.Header)
+	}
+	return pb.header
+}
+func (*pageBuffer) WriteHeader(int) {}
+
+// exportSlideSet writes the slide show, the handout, the additional zettel,
+// all images, and the files of reveal.js to the target. All references
+// between these files are relative, so that the slide show can be opened
+// without a running Zettelstore.
+func exportSlideSet(ctx context.Context, cfg *slidesConfig, slides *slideSet, target exportTarget) error {
+	author := slides.Author(cfg)
+	var page pageBuffer
+	rr := &revealRenderer{cfg: cfg, export: true}
+	rr.Prepare(ctx)
+	rr.Render(&page, slides, author)
+	if err := target.WriteFile(exportIndexFile, page.Bytes()); err != nil {
+		return err
+	}
+
+	page.Reset()
+	hr := &handoutRenderer{cfg: cfg, export: true}
+	hr.Prepare(ctx)
+	hr.Render(&page, slides, author)
+	if err := target.WriteFile(exportHandoutFile, page.Bytes()); err != nil {
+		return err
+	}
+
+	for _, zid := range slides.Additional() {
+		page.Reset()
+		renderExportZettel(&page, slides, slides.GetSlide(zid))
+		if err := target.WriteFile(exportZettelFile(zid), page.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := exportImages(slides, target); err != nil {
+		return err
+	}
+	return fs.WalkDir(revealjs, "revealjs", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := revealjs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return target.WriteFile(path, data)
+	})
+}
+
+// exportBeamer writes the slide show as a LaTeX Beamer document, together
+// with all images, to the target.
+func exportBeamer(ctx context.Context, cfg *slidesConfig, slides *slideSet, target exportTarget) error {
+	var page pageBuffer
+	br := &beamerRenderer{cfg: cfg}
+	br.Prepare(ctx)
+	br.Render(&page, slides, slides.Author(cfg))
+	if err := target.WriteFile(exportBeamerFile, page.Bytes()); err != nil {
+		return err
+	}
+	return exportImages(slides, target)
+}
+
+// exportImages writes all images of the slide set to the target. They are
+// sorted, so that the order of files within a zip file is always the same.
+func exportImages(slides *slideSet, target exportTarget) error {
+	zids := slides.Images()
+	slices.Sort(zids)
+	for _, zid := range zids {
+		if img, found := slides.GetImage(zid); found {
+			if err := target.WriteFile(exportImageFile(zid.String(), img.syntax), img.data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderExportZettel renders an additional zettel of the slide set as a page
+// of a static export.
+func renderExportZettel(w http.ResponseWriter, slides *slideSet, sl *slide) {
+	gen := newGenerator(slides, langDE, nil, false, false)
+	gen.SetExport(true)
+
+	headHTML := getHTMLHead()
+	headHTML.LastPair().
+		AppendBang(sx.MakeList(shtml.SymTitle, sx.MakeString(text.EvaluateInlineString(sl.title)))).
+		AppendBang(getPrefixedCSS(""))
+
+	headerHTML := sx.MakeList(
+		sxhtml.MakeSymbol("header"),
+		gen.TransformList(sl.title).Cons(shtml.SymH1),
+	)
+	articleHTML := sx.MakeList(sxhtml.MakeSymbol("article"))
+	curr := articleHTML
+	for elem := range gen.Transform(sl.content).Values() {
+		curr = curr.AppendBang(elem)
+	}
+	footerHTML := sx.MakeList(
+		sxhtml.MakeSymbol("footer"),
+		gen.Endnotes(),
+		sx.MakeList(shtml.SymP, getSimpleLink(exportIndexFile, sx.MakeList(sx.MakeString("Slides")))),
+	)
+	bodyHTML := sx.MakeList(shtml.SymBody, headerHTML, articleHTML, footerHTML)
+	gen.writeHTMLDocument(w, sl.lang, headHTML, bodyHTML)
+}
+
+// runExport exports a slide set from the command line. It returns the exit
+// code of the program: 0 on success, 1 if the export could not be written,
+// and 2 if the slide set could not be retrieved.
+func runExport(args []string) int {
+	cmd := newCommand("export")
+	output := cmd.fs.String("o", "", "Zip file or directory of the export (default: \"ZID.zip\")")
+	format := cmd.fs.String("format", "reveal", "Format of the export: reveal, beamer")
+	zid, ok := cmd.parse(args)
+	if !ok {
+		return 2
+	}
+	exportFn := exportSlideSet
+	switch *format {
+	case "reveal":
+	case "beamer":
+		exportFn = exportBeamer
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown export format %q\n", *format)
+		return 2
+	}
+	return cmd.run(zid, func(ctx context.Context, cfg *slidesConfig, slides *slideSet) int {
+		printWarnings(slides)
+		path := *output
+		if path == "" {
+			path = zid.String() + ".zip"
+		}
+		target, err := newExportTarget(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to create export %s: %v\n", path, err)
+			return 1
+		}
+		err = exportFn(ctx, cfg, slides, target)
+		if errClose := target.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write export %s: %v\n", path, err)
+			return 1
+		}
+		return 0
+	})
+}
@@ -17,7 +17,7 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -35,6 +35,7 @@ type htmlGenerator struct {
 	env      *shtml.Environment
 	s        *slideSet
 	curSlide *slideInfo
+	export   bool // Links and images refer to the files of a static export
 }
 
 // embedImage, extZettelLinks
@@ -157,7 +158,13 @@ func newGenerator(slides *slideSet, lang string, ren renderer, extZettelLinks, e
 				attr.SetCdr(avals)
 				return lst
 			}
-			if extZettelLinks {
+			if gen.export {
+				if ref := gen.ZettelRef(zid); err == nil && ref != "" {
+					avals = addClass(avals, "zettel")
+					attr.SetCdr(avals.Cons(sx.Cons(shtml.SymAttrHref, sx.MakeString(ref))))
+					return lst
+				}
+			} else if extZettelLinks {
 				// TODO: make link absolute
 				avals = addClass(avals, "zettel")
 				attr.SetCdr(avals.Cons(sx.Cons(shtml.SymAttrHref, sx.MakeString("/"+strZid))))
@@ -216,7 +223,7 @@ func newGenerator(slides *slideSet, lang string, ren renderer, extZettelLinks, e
 					shtml.SymEMBED,
 					sx.MakeList(
 						sx.Cons(shtml.SymAttrType, sx.MakeString("image/svg+xml")),
-						sx.Cons(shtml.SymAttrSrc, sx.MakeString("/"+string(strZid)+".svg")),
+						sx.Cons(shtml.SymAttrSrc, sx.MakeString(gen.imageRef(strZid, meta.ValueSyntaxSVG))),
 					),
 				),
 			)
@@ -236,7 +243,16 @@ func newGenerator(slides *slideSet, lang string, ren renderer, extZettelLinks, e
 				src = sb.String()
 			}
 		}
+		if src == "" && gen.s != nil && gen.export {
+			if img, found := gen.s.GetImage(zid); found {
+				src = gen.imageRef(strZid, img.syntax)
+			}
+		}
 		if src == "" {
+			if gen.export {
+				// Image was not collected, there is no file for it
+				return sx.Nil()
+			}
 			src = "/" + zid.String() + ".content"
 		}
 		srcAssoc.SetCdr(sx.MakeString(src))
@@ -255,11 +271,36 @@ func rebind(th *shtml.Evaluator, sym *sx.Symbol, fn func(sx.Vector, *shtml.Envir
 
 func (gen *htmlGenerator) SetUnique(s string)            { gen.tr.SetUnique(s) }
 func (gen *htmlGenerator) SetCurrentSlide(si *slideInfo) { gen.curSlide = si }
+func (gen *htmlGenerator) SetExport(export bool)         { gen.export = export }
+
+// ZettelRef returns the reference to the page of a zettel, or the empty string
+// if there is no such page. Within a static export, only the additional
+// zettel of the slide set have a page.
+func (gen *htmlGenerator) ZettelRef(zid id.Zid) string {
+	if !gen.export {
+		return zid.String()
+	}
+	if gen.s != nil && gen.s.IsAdditional(zid) {
+		return exportZettelFile(zid)
+	}
+	return ""
+}
+
+// imageRef returns the reference to an image that is not embedded.
+func (gen *htmlGenerator) imageRef(strZid, syntax string) string {
+	if gen.export {
+		return exportImageFile(strZid, syntax)
+	}
+	if syntax == meta.ValueSyntaxSVG {
+		return "/" + strZid + ".svg"
+	}
+	return "/" + strZid + ".content"
+}
 
 func (gen *htmlGenerator) Transform(astLst *sx.Pair) *sx.Pair {
 	result, err := gen.tr.Evaluate(astLst, gen.env)
 	if err != nil {
-		log.Println("ETRA", err)
+		slog.Error("Unable to transform zettel", "err", err)
 	}
 	return result
 }
@@ -267,7 +308,7 @@ func (gen *htmlGenerator) Transform(astLst *sx.Pair) *sx.Pair {
 func (gen *htmlGenerator) TransformList(astLst *sx.Pair) *sx.Pair {
 	result, err := gen.tr.EvaluateList(sx.Collect(astLst.Values()), gen.env)
 	if err != nil {
-		log.Println("ETRL", err)
+		slog.Error("Unable to transform list", "err", err)
 	}
 	return result
 }
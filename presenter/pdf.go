@@ -0,0 +1,428 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	goimage "image"
+	"image/color"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	_ "image/png"  // Register PNG decoder
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Size of an A4 page, in points.
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+// pdfFont is one of the standard fonts that every PDF viewer provides.
+type pdfFont int
+
+// Fonts used for the PDF handout.
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontMono
+	numFonts
+)
+
+var pdfFontNames = [numFonts]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique", "Courier"}
+
+// Width returns the width of a WinAnsi encoded string, in points.
+func (f pdfFont) Width(s string, size float64) float64 {
+	var widths *[224]uint16
+	switch f {
+	case fontRegular, fontItalic:
+		widths = &widthsHelvetica
+	case fontBold, fontBoldItalic:
+		widths = &widthsHelveticaBold
+	default:
+		return float64(len(s)) * 0.6 * size
+	}
+	w := 0
+	for i := range len(s) {
+		if ch := s[i]; ch >= 32 {
+			w += int(widths[ch-32])
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// encodeWinAnsi converts a string into the WinAnsi encoding of the standard
+// fonts. Characters that cannot be encoded are replaced by a question mark.
+func encodeWinAnsi(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\u00ad': // Soft hyphen
+		case r < 0x80 || (0xa0 <= r && r <= 0xff):
+			sb.WriteByte(byte(r))
+		default:
+			if ch, found := winAnsiSpecial[r]; found {
+				sb.WriteByte(ch)
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// winAnsiSpecial maps the characters 0x80 to 0x9f of the WinAnsi encoding,
+// and some other characters to similar ones.
+var winAnsiSpecial = map[rune]byte{
+	'\u20ac': 0x80, '\u201a': 0x82, '\u0192': 0x83, '\u201e': 0x84, '\u2026': 0x85, '\u2020': 0x86, '\u2021': 0x87,
+	'\u02c6': 0x88, '\u2030': 0x89, '\u0160': 0x8a, '\u2039': 0x8b, '\u0152': 0x8c, '\u017d': 0x8e,
+	'\u2018': 0x91, '\u2019': 0x92, '\u201c': 0x93, '\u201d': 0x94, '\u2022': 0x95, '\u2013': 0x96, '\u2014': 0x97,
+	'\u02dc': 0x98, '\u2122': 0x99, '\u0161': 0x9a, '\u203a': 0x9b, '\u0153': 0x9c, '\u017e': 0x9e, '\u0178': 0x9f,
+	'\u2009': ' ', '\u202f': ' ', '\u2212': '-', '\u2011': '-',
+}
+
+// pdfImage is an image XObject.
+type pdfImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+// newPDFImage converts image data into an image XObject. JPEG images are
+// stored as they are, all other images are decoded and stored as compressed
+// RGB data, with transparent pixels on a white background.
+func newPDFImage(data []byte) (*pdfImage, error) {
+	if cfg, format, err := goimage.DecodeConfig(bytes.NewReader(data)); err == nil && format == "jpeg" {
+		switch cfg.ColorModel {
+		case color.YCbCrModel, color.RGBAModel:
+			return &pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceRGB", filter: "DCTDecode", data: data}, nil
+		case color.GrayModel:
+			return &pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceGray", filter: "DCTDecode", data: data}, nil
+		}
+	}
+	img, _, err := goimage.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, 3*bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			bg := 0xffff - a
+			rgb = append(rgb, byte((r+bg)>>8), byte((g+bg)>>8), byte((b+bg)>>8))
+		}
+	}
+	return &pdfImage{
+		width:      bounds.Dx(),
+		height:     bounds.Dy(),
+		colorSpace: "DeviceRGB",
+		filter:     "FlateDecode",
+		data:       compress(rgb),
+	}, nil
+}
+
+// pdfInfo contains the metadata of a PDF document.
+type pdfInfo struct {
+	Title     string
+	Subject   string
+	Author    string
+	Copyright string
+	License   string
+	Created   time.Time
+}
+
+// pdfDocument is a PDF document, which is created page by page.
+type pdfDocument struct {
+	info   pdfInfo
+	pages  []*strings.Builder
+	images []*pdfImage
+}
+
+func newPDFDocument(info pdfInfo) *pdfDocument { return &pdfDocument{info: info} }
+
+// AddPage starts a new page. All further output goes to this page.
+func (doc *pdfDocument) AddPage() { doc.pages = append(doc.pages, &strings.Builder{}) }
+
+// NumPages returns the number of pages.
+func (doc *pdfDocument) NumPages() int { return len(doc.pages) }
+
+// page returns the content of the given page, starting with 1. Page number
+// zero denotes the current page.
+func (doc *pdfDocument) page(pageNo int) *strings.Builder {
+	if len(doc.pages) == 0 {
+		doc.AddPage()
+	}
+	if pageNo <= 0 || pageNo > len(doc.pages) {
+		pageNo = len(doc.pages)
+	}
+	return doc.pages[pageNo-1]
+}
+
+// Text writes text to the current page. The baseline of the text starts at
+// position x, y. Rise moves the text up, e.g. for superscript.
+func (doc *pdfDocument) Text(x, y float64, font pdfFont, size, rise float64, s string) {
+	doc.TextOnPage(0, x, y, font, size, rise, s)
+}
+
+// TextOnPage writes text to the given page. A page number of zero denotes
+// the current page.
+func (doc *pdfDocument) TextOnPage(pageNo int, x, y float64, font pdfFont, size, rise float64, s string) {
+	p := doc.page(pageNo)
+	_, _ = fmt.Fprintf(p, "BT /F%d %s Tf %s Ts %s %s Td ", font+1, pdfNum(size), pdfNum(rise), pdfNum(x), pdfNum(y))
+	writePDFString(p, s)
+	p.WriteString(" Tj ET\n")
+}
+
+// Line draws a line on the current page, with the given width and gray level.
+func (doc *pdfDocument) Line(x1, y1, x2, y2, width, gray float64) {
+	_, _ = fmt.Fprintf(doc.page(0), "%s G %s w %s %s m %s %s l S 0 G\n",
+		pdfNum(gray), pdfNum(width), pdfNum(x1), pdfNum(y1), pdfNum(x2), pdfNum(y2))
+}
+
+// Rect fills a rectangle on the current page with the given gray level.
+func (doc *pdfDocument) Rect(x, y, w, h, gray float64) {
+	_, _ = fmt.Fprintf(doc.page(0), "%s g %s %s %s %s re f 0 g\n", pdfNum(gray), pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h))
+}
+
+// Image places an image on the current page. Its lower left corner is at
+// position x, y.
+func (doc *pdfDocument) Image(img *pdfImage, x, y, w, h float64) {
+	imgNo := 0
+	for i, other := range doc.images {
+		if other == img {
+			imgNo = i + 1
+			break
+		}
+	}
+	if imgNo == 0 {
+		doc.images = append(doc.images, img)
+		imgNo = len(doc.images)
+	}
+	_, _ = fmt.Fprintf(doc.page(0), "q %s 0 0 %s %s %s cm /Im%d Do Q\n", pdfNum(w), pdfNum(h), pdfNum(x), pdfNum(y), imgNo)
+}
+
+// WriteTo writes the PDF document.
+func (doc *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	if len(doc.pages) == 0 {
+		doc.AddPage()
+	}
+	// Object numbers: 1 catalog, 2 page tree, 3 info, then fonts, images,
+	// and finally page and content of every page.
+	const objFirstFont = 4
+	objFirstImage := objFirstFont + int(numFonts)
+	objFirstPage := objFirstImage + len(doc.images)
+
+	pw := pdfWriter{}
+	pw.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	pw.beginObj()
+	pw.buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>")
+	pw.endObj()
+
+	pw.beginObj()
+	pw.buf.WriteString("<< /Type /Pages /Kids [")
+	for i := range doc.pages {
+		_, _ = fmt.Fprintf(&pw.buf, " %d 0 R", objFirstPage+2*i)
+	}
+	_, _ = fmt.Fprintf(&pw.buf, " ] /Count %d >>", len(doc.pages))
+	pw.endObj()
+
+	pw.beginObj()
+	doc.writeInfo(&pw.buf)
+	pw.endObj()
+
+	for _, name := range pdfFontNames {
+		pw.beginObj()
+		_, _ = fmt.Fprintf(&pw.buf, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name)
+		pw.endObj()
+	}
+	for _, img := range doc.images {
+		pw.beginObj()
+		_, _ = fmt.Fprintf(&pw.buf, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>\nstream\n",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data))
+		pw.buf.Write(img.data)
+		pw.buf.WriteString("\nendstream")
+		pw.endObj()
+	}
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for i := range numFonts {
+		_, _ = fmt.Fprintf(&resources, " /F%d %d 0 R", i+1, objFirstFont+int(i))
+	}
+	resources.WriteString(" >>")
+	if len(doc.images) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i := range doc.images {
+			_, _ = fmt.Fprintf(&resources, " /Im%d %d 0 R", i+1, objFirstImage+i)
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+	for _, p := range doc.pages {
+		objPage := pw.beginObj()
+		_, _ = fmt.Fprintf(&pw.buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), resources.String(), objPage+1)
+		pw.endObj()
+
+		content := compress([]byte(p.String()))
+		pw.beginObj()
+		_, _ = fmt.Fprintf(&pw.buf, "<< /Filter /FlateDecode /Length %d >>\nstream\n", len(content))
+		pw.buf.Write(content)
+		pw.buf.WriteString("\nendstream")
+		pw.endObj()
+	}
+
+	xref := pw.buf.Len()
+	_, _ = fmt.Fprintf(&pw.buf, "xref\n0 %d\n0000000000 65535 f\r\n", len(pw.offsets)+1)
+	for _, offset := range pw.offsets {
+		_, _ = fmt.Fprintf(&pw.buf, "%010d 00000 n\r\n", offset)
+	}
+	_, _ = fmt.Fprintf(&pw.buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+	return pw.buf.WriteTo(w)
+}
+
+func (doc *pdfDocument) writeInfo(buf *bytes.Buffer) {
+	buf.WriteString("<< /Producer (Zettel Presenter)")
+	for _, entry := range []struct{ key, val string }{
+		{"Title", doc.info.Title},
+		{"Subject", doc.info.Subject},
+		{"Author", doc.info.Author},
+		{"Copyright", doc.info.Copyright},
+		{"License", doc.info.License},
+	} {
+		if entry.val != "" {
+			_, _ = fmt.Fprintf(buf, " /%s ", entry.key)
+			writePDFTextString(buf, entry.val)
+		}
+	}
+	if ts := doc.info.Created; !ts.IsZero() {
+		_, _ = fmt.Fprintf(buf, " /CreationDate (D:%s)", ts.Format("20060102150405"))
+	}
+	buf.WriteString(" >>")
+}
+
+// pdfWriter stores the objects of a PDF document and their offsets.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (pw *pdfWriter) beginObj() int {
+	pw.offsets = append(pw.offsets, pw.buf.Len())
+	objNo := len(pw.offsets)
+	_, _ = fmt.Fprintf(&pw.buf, "%d 0 obj\n", objNo)
+	return objNo
+}
+func (pw *pdfWriter) endObj() { pw.buf.WriteString("\nendobj\n") }
+
+// writePDFString writes an encoded string as a PDF string literal.
+func writePDFString(sb *strings.Builder, s string) {
+	sb.WriteByte('(')
+	for i := range len(s) {
+		switch ch := s[i]; ch {
+		case '\\', '(', ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	sb.WriteByte(')')
+}
+
+// writePDFTextString writes a string of the document metadata. Strings with
+// non-ASCII characters are written in UTF-16.
+func writePDFTextString(buf *bytes.Buffer, s string) {
+	for i := range len(s) {
+		if s[i] >= 0x80 {
+			buf.WriteString("<FEFF")
+			for _, u := range utf16.Encode([]rune(s)) {
+				_, _ = fmt.Fprintf(buf, "%04X", u)
+			}
+			buf.WriteByte('>')
+			return
+		}
+	}
+	var sb strings.Builder
+	writePDFString(&sb, s)
+	buf.WriteString(sb.String())
+}
+
+func pdfNum(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+// Widths of the WinAnsi characters 0x20 to 0xff of the standard fonts, in
+// thousandths of the font size.
+var widthsHelvetica = [224]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // 0x20
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0x30
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // 0x40
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // 0x50
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // 0x60
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, 350, // 0x70
+	556, 350, 222, 556, 333, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350, // 0x80
+	350, 222, 222, 333, 333, 350, 556, 1000, 333, 1000, 500, 333, 944, 350, 500, 667, // 0x90
+	278, 333, 556, 556, 556, 556, 260, 556, 333, 737, 370, 556, 584, 333, 737, 333, // 0xa0
+	400, 584, 333, 333, 333, 556, 537, 278, 333, 333, 365, 556, 834, 834, 834, 611, // 0xb0
+	667, 667, 667, 667, 667, 667, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278, // 0xc0
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611, // 0xd0
+	556, 556, 556, 556, 556, 556, 889, 500, 556, 556, 556, 556, 278, 278, 278, 278, // 0xe0
+	556, 556, 556, 556, 556, 556, 556, 584, 611, 556, 556, 556, 556, 500, 556, 500, // 0xf0
+}
+
+var widthsHelveticaBold = [224]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // 0x20
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611, // 0x30
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778, // 0x40
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, // 0x50
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, // 0x60
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, 350, // 0x70
+	556, 350, 278, 556, 500, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350, // 0x80
+	350, 278, 278, 500, 500, 350, 556, 1000, 333, 1000, 556, 333, 944, 350, 500, 667, // 0x90
+	278, 333, 556, 556, 556, 556, 280, 556, 333, 737, 370, 556, 584, 333, 737, 333, // 0xa0
+	400, 584, 333, 333, 333, 611, 556, 278, 333, 333, 365, 556, 834, 834, 834, 611, // 0xb0
+	722, 722, 722, 722, 722, 722, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278, // 0xc0
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611, // 0xd0
+	556, 556, 556, 556, 556, 556, 889, 556, 556, 556, 556, 556, 278, 278, 278, 278, // 0xe0
+	611, 611, 611, 611, 611, 611, 611, 584, 611, 611, 611, 611, 611, 556, 611, 556, // 0xf0
+}
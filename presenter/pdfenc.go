@@ -0,0 +1,899 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/text"
+	"t73f.de/r/zsx"
+)
+
+// Layout of the PDF handout, in points.
+const (
+	pdfMargin   = 56.0
+	pdfRight    = pdfPageWidth - pdfMargin
+	pdfTop      = pdfPageHeight - pdfMargin
+	pdfBottom   = pdfMargin
+	pdfFontSize = 11.0
+	pdfCodeSize = 9.0
+	pdfNoteSize = 9.0
+	pdfLeading  = 1.3 // Line height, relative to the font size
+	pdfIndent   = 18.0
+	pdfParaSkip = 6.0
+	pdfPixel    = 0.75 // Size of an image pixel
+)
+
+type pdfRenderer struct{ cfg *slidesConfig }
+
+func (*pdfRenderer) Role() string            { return SlideRoleHandout }
+func (*pdfRenderer) Prepare(context.Context) {}
+func (*pdfRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
+	doc := makePDFHandout(slides, author)
+	w.Header().Set("Content-Type", "application/pdf")
+	if _, err := doc.WriteTo(w); err != nil {
+		slog.Error("Unable to write PDF handout", "zid", slides.zid, "err", err)
+	}
+}
+
+// makePDFHandout creates the handout as a PDF document. It contains the same
+// slides as the HTML handout.
+func makePDFHandout(slides *slideSet, author string) *pdfDocument {
+	title := slides.Title()
+	info := pdfInfo{
+		Author:    author,
+		Copyright: slides.Copyright(),
+		License:   slides.License(),
+		Created:   slides.GetPublished(),
+	}
+	if title != nil {
+		info.Title = text.EvaluateInlineString(title)
+	}
+	subtitle := slides.Subtitle()
+	if subtitle != nil {
+		info.Subject = text.EvaluateInlineString(subtitle)
+	}
+	pl := pdfLayout{
+		doc:    newPDFDocument(info),
+		slides: slides,
+		images: map[id.Zid]*pdfImage{},
+	}
+
+	offset := 1
+	if title != nil {
+		offset++
+		pl.titlePage(title, subtitle, info)
+	}
+	pl.newPage()
+	for si := slides.Slides(SlideRoleHandout, offset); si != nil; si = si.Next() {
+		pl.slideHeading(si)
+		pl.blockList(si.Slide.content)
+	}
+	pl.endnotes()
+	pl.pageNumbers()
+	return pl.doc
+}
+
+// runPDF writes the PDF handout of a slide set from the command line. It
+// returns the exit code of the program: 0 on success, 1 if the PDF could not
+// be written, and 2 if the slide set could not be retrieved.
+func runPDF(args []string) int {
+	cmd := newCommand("pdf")
+	output := cmd.fs.String("o", "", "PDF file of the handout, \"-\" for standard output (default: \"ZID.pdf\")")
+	zid, ok := cmd.parse(args)
+	if !ok {
+		return 2
+	}
+	return cmd.run(zid, func(_ context.Context, cfg *slidesConfig, slides *slideSet) int {
+		printWarnings(slides)
+		doc := makePDFHandout(slides, slides.Author(cfg))
+		path := *output
+		if path == "" {
+			path = zid.String() + ".pdf"
+		}
+		var err error
+		if path == "-" {
+			_, err = doc.WriteTo(os.Stdout)
+		} else {
+			var f *os.File
+			if f, err = os.Create(path); err == nil {
+				_, err = doc.WriteTo(f)
+				if errClose := f.Close(); err == nil {
+					err = errClose
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write PDF %s: %v\n", path, err)
+			return 1
+		}
+		return 0
+	})
+}
+
+// pdfStyle is the style of a piece of text.
+type pdfStyle struct {
+	font pdfFont
+	size float64
+	rise float64
+}
+
+func (st pdfStyle) emph() pdfStyle {
+	switch st.font {
+	case fontRegular:
+		st.font = fontItalic
+	case fontBold:
+		st.font = fontBoldItalic
+	}
+	return st
+}
+
+func (st pdfStyle) strong() pdfStyle {
+	switch st.font {
+	case fontRegular:
+		st.font = fontBold
+	case fontItalic:
+		st.font = fontBoldItalic
+	}
+	return st
+}
+
+func (st pdfStyle) mono() pdfStyle {
+	st.font = fontMono
+	st.size *= 0.9
+	return st
+}
+
+func (st pdfStyle) super() pdfStyle {
+	st.rise += st.size * 0.35
+	st.size *= 0.7
+	return st
+}
+
+func (st pdfStyle) sub() pdfStyle {
+	st.rise -= st.size * 0.15
+	st.size *= 0.7
+	return st
+}
+
+// pdfPiece is a part of a text with the same style. Its text is WinAnsi
+// encoded.
+type pdfPiece struct {
+	text  string
+	style pdfStyle
+	width float64
+}
+
+// pdfBox is a part of a line that must not be split, e.g. a word.
+type pdfBox struct {
+	pieces     []pdfPiece
+	width      float64
+	space      bool    // Box is preceded by a space
+	spaceWidth float64 // Width of the preceding space
+	image      *pdfImage
+	newline    bool // Box ends the current line
+}
+
+// pdfInlines collects the boxes of a paragraph.
+type pdfInlines struct {
+	boxes []*pdfBox
+	space bool // Next text is preceded by a space
+}
+
+func (pi *pdfInlines) addText(s string, st pdfStyle) {
+	start := 0
+	for i, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' {
+			pi.addWord(s[start:i], st)
+			pi.space = true
+			start = i + 1
+		}
+	}
+	pi.addWord(s[start:], st)
+}
+
+func (pi *pdfInlines) addWord(word string, st pdfStyle) {
+	if word == "" {
+		return
+	}
+	enc := encodeWinAnsi(word)
+	piece := pdfPiece{text: enc, style: st, width: st.font.Width(enc, st.size)}
+	if n := len(pi.boxes); n > 0 && !pi.space {
+		if last := pi.boxes[n-1]; last.image == nil && !last.newline {
+			last.pieces = append(last.pieces, piece)
+			last.width += piece.width
+			return
+		}
+	}
+	pi.boxes = append(pi.boxes, &pdfBox{
+		pieces:     []pdfPiece{piece},
+		width:      piece.width,
+		space:      pi.space,
+		spaceWidth: st.font.Width(" ", st.size),
+	})
+	pi.space = false
+}
+
+func (pi *pdfInlines) addSpace() { pi.space = true }
+func (pi *pdfInlines) addNewline() {
+	pi.boxes = append(pi.boxes, &pdfBox{newline: true})
+	pi.space = false
+}
+func (pi *pdfInlines) addImage(img *pdfImage) {
+	pi.boxes = append(pi.boxes, &pdfBox{image: img})
+	pi.space = false
+}
+
+// pdfLine is a line of text, or an image.
+type pdfLine struct {
+	items  []pdfLineItem
+	width  float64
+	size   float64 // Largest font size of the line
+	image  *pdfImage
+	broken bool // Line was broken by a hard line break
+}
+
+type pdfLineItem struct {
+	x     float64
+	piece pdfPiece
+}
+
+func (ln *pdfLine) add(x float64, box *pdfBox) {
+	for _, piece := range box.pieces {
+		ln.items = append(ln.items, pdfLineItem{x: x, piece: piece})
+		x += piece.width
+		ln.size = max(ln.size, piece.style.size)
+	}
+	ln.width = x
+}
+
+// breakLines distributes the boxes of a paragraph to lines of the given width.
+func breakLines(boxes []*pdfBox, width float64) []*pdfLine {
+	var lines []*pdfLine
+	curr := &pdfLine{}
+	flush := func() {
+		if len(curr.items) > 0 || curr.broken {
+			lines = append(lines, curr)
+		}
+		curr = &pdfLine{}
+	}
+	for _, box := range boxes {
+		switch {
+		case box.image != nil:
+			flush()
+			lines = append(lines, &pdfLine{image: box.image})
+		case box.newline:
+			curr.broken = true
+			flush()
+		default:
+			x := curr.width
+			if len(curr.items) > 0 && box.space {
+				x += box.spaceWidth
+			}
+			if len(curr.items) > 0 && x+box.width > width {
+				flush()
+				x = 0
+			}
+			if len(curr.items) == 0 && box.width > width {
+				parts := splitBox(box, width)
+				for _, part := range parts[:len(parts)-1] {
+					curr.add(0, part)
+					flush()
+				}
+				box = parts[len(parts)-1]
+			}
+			curr.add(x, box)
+		}
+	}
+	flush()
+	return lines
+}
+
+// splitBox splits a box that is wider than a line, e.g. a long URL.
+func splitBox(box *pdfBox, width float64) []*pdfBox {
+	var result []*pdfBox
+	curr := &pdfBox{}
+	for _, piece := range box.pieces {
+		start := 0
+		w := 0.0
+		for i := range len(piece.text) {
+			cw := piece.style.font.Width(piece.text[i:i+1], piece.style.size)
+			if curr.width+w+cw > width && curr.width+w > 0 {
+				curr.pieces = append(curr.pieces, pdfPiece{text: piece.text[start:i], style: piece.style, width: w})
+				curr.width += w
+				result = append(result, curr)
+				curr = &pdfBox{}
+				start, w = i, 0
+			}
+			w += cw
+		}
+		curr.pieces = append(curr.pieces, pdfPiece{text: piece.text[start:], style: piece.style, width: w})
+		curr.width += w
+	}
+	return append(result, curr)
+}
+
+// pdfLayout places the content of the slides on the pages of the document.
+type pdfLayout struct {
+	doc    *pdfDocument
+	slides *slideSet
+	y      float64   // Top of the next line
+	left   float64   // Left edge of the text, depends on indentation
+	bars   []float64 // Position of vertical bars, e.g. for quotations
+	marker string    // List marker, placed before the next line
+	notes  []*sx.Pair
+	images map[id.Zid]*pdfImage
+}
+
+func (pl *pdfLayout) newPage() {
+	pl.doc.AddPage()
+	pl.y = pdfTop
+	if pl.left < pdfMargin {
+		pl.left = pdfMargin
+	}
+}
+
+// ensure starts a new page, if there is not enough space left.
+func (pl *pdfLayout) ensure(h float64) {
+	if pl.y-h < pdfBottom && pl.y < pdfTop {
+		pl.newPage()
+	}
+}
+
+// skip adds vertical space.
+func (pl *pdfLayout) skip(h float64) {
+	if pl.y >= pdfTop {
+		return
+	}
+	if pl.y-h < pdfBottom {
+		pl.newPage()
+		return
+	}
+	pl.drawBars(h)
+	pl.y -= h
+}
+
+func (pl *pdfLayout) drawBars(h float64) {
+	for _, x := range pl.bars {
+		pl.doc.Line(x, pl.y, x, pl.y-h, 2, 0.8)
+	}
+}
+
+// withIndent lays out some content with additional indentation and an
+// optional vertical bar.
+func (pl *pdfLayout) withIndent(bar bool, fn func()) {
+	if bar {
+		pl.bars = append(pl.bars, pl.left+pdfIndent/3)
+	}
+	pl.left += pdfIndent
+	fn()
+	pl.left -= pdfIndent
+	if bar {
+		pl.bars = pl.bars[:len(pl.bars)-1]
+	}
+}
+
+// lineHeight returns the height of a line and the size of its image, if any.
+func (pl *pdfLayout) lineHeight(ln *pdfLine, width float64) (float64, float64, float64) {
+	if img := ln.image; img != nil {
+		w, h := float64(img.width)*pdfPixel, float64(img.height)*pdfPixel
+		maxH := (pdfTop - pdfBottom) * 0.6
+		scale := math.Min(1, math.Min(width/w, maxH/h))
+		return h*scale + pdfParaSkip, w * scale, h * scale
+	}
+	size := ln.size
+	if size == 0 {
+		size = pdfFontSize
+	}
+	return size * pdfLeading, 0, 0
+}
+
+// drawLine draws a line, with its top at the current position.
+func (pl *pdfLayout) drawLine(ln *pdfLine, x, width float64) float64 {
+	h, imgW, imgH := pl.lineHeight(ln, width)
+	if ln.image != nil {
+		pl.doc.Image(ln.image, x, pl.y-imgH, imgW, imgH)
+		return h
+	}
+	baseline := pl.y - ln.size
+	for _, item := range ln.items {
+		st := item.piece.style
+		pl.doc.Text(x+item.x, baseline, st.font, st.size, st.rise, item.piece.text)
+	}
+	return h
+}
+
+// drawMarker draws a pending list marker left of the current position.
+func (pl *pdfLayout) drawMarker() {
+	if pl.marker != "" {
+		marker := encodeWinAnsi(pl.marker)
+		pl.doc.Text(pl.left-4-fontRegular.Width(marker, pdfFontSize), pl.y-pdfFontSize, fontRegular, pdfFontSize, 0, marker)
+		pl.marker = ""
+	}
+}
+
+// paragraph places the boxes of a paragraph at the current position.
+func (pl *pdfLayout) paragraph(pi *pdfInlines) {
+	width := pdfRight - pl.left
+	for _, ln := range breakLines(pi.boxes, width) {
+		h, _, _ := pl.lineHeight(ln, width)
+		pl.ensure(h)
+		pl.drawMarker()
+		pl.drawLine(ln, pl.left, width)
+		pl.drawBars(h)
+		pl.y -= h
+	}
+}
+
+func (pl *pdfLayout) titlePage(title, subtitle *sx.Pair, info pdfInfo) {
+	pl.newPage()
+	pl.y = pdfTop - 120
+	var pi pdfInlines
+	pl.inlines(&pi, title, pdfStyle{font: fontBold, size: 24})
+	pl.paragraph(&pi)
+	if subtitle != nil {
+		pl.skip(pdfParaSkip)
+		pi = pdfInlines{}
+		pl.inlines(&pi, subtitle, pdfStyle{font: fontRegular, size: 16})
+		pl.paragraph(&pi)
+	}
+	pl.skip(36)
+	st := pdfStyle{font: fontRegular, size: 12}
+	for _, s := range []string{info.Author, info.Copyright, info.License} {
+		if s != "" {
+			pi = pdfInlines{}
+			pi.addText(s, st)
+			pl.paragraph(&pi)
+		}
+	}
+	if ts := info.Created; ts.After(time.Time{}) {
+		pi = pdfInlines{}
+		pi.addText("Update: "+ts.Format("2006-01-02 15:04"), st)
+		pl.paragraph(&pi)
+	}
+}
+
+func (pl *pdfLayout) slideHeading(si *slideInfo) {
+	title := si.Slide.title
+	if title == nil {
+		return
+	}
+	pl.ensure(4 * pdfFontSize * pdfLeading)
+	pl.skip(2 * pdfParaSkip)
+	var pi pdfInlines
+	pl.inlines(&pi, title, pdfStyle{font: fontBold, size: 16})
+	if from, to := slideNoRange(si); from > 0 {
+		st := pdfStyle{font: fontRegular, size: 10}
+		if from < to {
+			pi.addText(fmt.Sprintf(" (S.%d–%d)", from, to), st)
+		} else {
+			pi.addText(fmt.Sprintf(" (S.%d)", from), st)
+		}
+	}
+	pl.paragraph(&pi)
+	pl.skip(pdfParaSkip)
+}
+
+func (pl *pdfLayout) endnotes() {
+	if len(pl.notes) == 0 {
+		return
+	}
+	pl.ensure(4 * pdfNoteSize * pdfLeading)
+	pl.skip(2 * pdfParaSkip)
+	pl.doc.Line(pl.left, pl.y, pl.left+pdfIndent*4, pl.y, 0.5, 0)
+	pl.skip(pdfParaSkip)
+	pl.withIndent(false, func() {
+		// Endnotes may contain endnotes, therefore pl.notes may grow.
+		for i := 0; i < len(pl.notes); i++ {
+			var pi pdfInlines
+			pl.inlines(&pi, pl.notes[i], pdfStyle{font: fontRegular, size: pdfNoteSize})
+			pl.marker = strconv.Itoa(i+1) + "."
+			pl.paragraph(&pi)
+			pl.marker = ""
+		}
+	})
+}
+
+func (pl *pdfLayout) pageNumbers() {
+	n := pl.doc.NumPages()
+	for pageNo := 1; pageNo <= n; pageNo++ {
+		s := fmt.Sprintf("%d / %d", pageNo, n)
+		w := fontRegular.Width(s, pdfNoteSize)
+		pl.doc.TextOnPage(pageNo, (pdfPageWidth-w)/2, pdfBottom-24, fontRegular, pdfNoteSize, 0, s)
+	}
+}
+
+// getSymbolNode returns the symbol and the arguments of a node.
+func getSymbolNode(obj sx.Object) (*sx.Symbol, *sx.Pair) {
+	node, isPair := sx.GetPair(obj)
+	if !isPair || node == nil {
+		return nil, nil
+	}
+	sym, isSymbol := sx.GetSymbol(node.Car())
+	if !isSymbol {
+		return nil, nil
+	}
+	return sym, node.Tail()
+}
+
+// isBlockList returns true, if the object is a list of block nodes.
+func isBlockList(obj sx.Object) bool {
+	lst, isPair := sx.GetPair(obj)
+	if !isPair || lst == nil {
+		return false
+	}
+	sym, _ := getSymbolNode(lst.Car())
+	return sym != nil
+}
+
+// blockList places all block nodes of a list. The list may start with the
+// symbol BLOCK.
+func (pl *pdfLayout) blockList(lst *sx.Pair) {
+	for obj := range lst.Values() {
+		if sym, args := getSymbolNode(obj); sym != nil {
+			if sym.IsEqualSymbol(zsx.SymBlock) {
+				pl.blockList(args)
+			} else {
+				pl.block(sym, args)
+			}
+		} else if isBlockList(obj) {
+			inner, _ := sx.GetPair(obj)
+			pl.blockList(inner)
+		}
+	}
+}
+
+func (pl *pdfLayout) block(sym *sx.Symbol, args *sx.Pair) {
+	switch {
+	case sym.IsEqualSymbol(zsx.SymPara):
+		var pi pdfInlines
+		pl.inlines(&pi, args, pdfStyle{font: fontRegular, size: pdfFontSize})
+		pl.paragraph(&pi)
+		pl.skip(pdfParaSkip)
+	case sym.IsEqualSymbol(zsx.SymHeading):
+		pl.heading(args)
+	case sym.IsEqualSymbol(zsx.SymThematic):
+		if !zsx.GetAttributes(args.Car()).HasDefault() {
+			pl.skip(pdfParaSkip)
+			pl.ensure(pdfParaSkip)
+			pl.doc.Line(pl.left, pl.y, pdfRight, pl.y, 0.5, 0.6)
+			pl.skip(2 * pdfParaSkip)
+		}
+	case sym.IsEqualSymbol(zsx.SymListOrdered):
+		pl.list(args, true)
+	case sym.IsEqualSymbol(zsx.SymListUnordered):
+		pl.list(args, false)
+	case sym.IsEqualSymbol(zsx.SymListQuote):
+		pl.withIndent(true, func() {
+			for item := range args.Values() {
+				if lst, isPair := sx.GetPair(item); isPair && isBlockList(item) {
+					pl.blockList(lst)
+				}
+			}
+		})
+	case sym.IsEqualSymbol(zsx.SymDescription):
+		pl.description(args)
+	case sym.IsEqualSymbol(zsx.SymTable):
+		pl.table(args)
+	case sym.IsEqualSymbol(zsx.SymVerbatimCode), sym.IsEqualSymbol(zsx.SymVerbatimEval),
+		sym.IsEqualSymbol(zsx.SymVerbatimMath), sym.IsEqualSymbol(zsx.SymVerbatimZettel):
+		pl.code(getFirstString(args))
+	case sym.IsEqualSymbol(zsx.SymRegionBlock):
+		pl.region(args)
+	case sym.IsEqualSymbol(zsx.SymRegionQuote):
+		pl.withIndent(true, func() { pl.regionContent(args, true) })
+	case sym.IsEqualSymbol(zsx.SymRegionVerse):
+		pl.withIndent(false, func() { pl.regionContent(args, false) })
+	}
+}
+
+func (pl *pdfLayout) heading(args *sx.Pair) {
+	num, isNumber := sx.GetNumber(args.Car())
+	if !isNumber {
+		return
+	}
+	level, _ := num.(sx.Int64)
+	if level == 1 && zsx.GetAttributes(args.Tail().Car()).HasDefault() {
+		// Like the HTML handout, headings that split a slide are not shown.
+		return
+	}
+	size := pdfFontSize
+	switch level {
+	case 1:
+		size = 14
+	case 2:
+		size = 13
+	case 3:
+		size = 12
+	}
+	pl.ensure(3 * size * pdfLeading)
+	pl.skip(pdfParaSkip)
+	var pi pdfInlines
+	pl.inlines(&pi, args.Tail(), pdfStyle{font: fontBold, size: size})
+	pl.paragraph(&pi)
+	pl.skip(pdfParaSkip / 2)
+}
+
+func (pl *pdfLayout) list(args *sx.Pair, ordered bool) {
+	pl.withIndent(false, func() {
+		n := 0
+		for item := range args.Values() {
+			lst, isPair := sx.GetPair(item)
+			if !isPair || !isBlockList(item) {
+				continue
+			}
+			n++
+			if ordered {
+				pl.marker = strconv.Itoa(n) + "."
+			} else {
+				pl.marker = "•"
+			}
+			pl.blockList(lst)
+			pl.marker = ""
+		}
+	})
+}
+
+func (pl *pdfLayout) description(args *sx.Pair) {
+	for obj := range args.Values() {
+		if sym, termArgs := getSymbolNode(obj); sym != nil && sym.IsEqualSymbol(zsx.SymInline) {
+			var pi pdfInlines
+			pl.inlines(&pi, termArgs, pdfStyle{font: fontBold, size: pdfFontSize})
+			pl.paragraph(&pi)
+			continue
+		}
+		if lst, isPair := sx.GetPair(obj); isPair && isBlockList(obj) {
+			pl.withIndent(false, func() { pl.blockList(lst) })
+		}
+	}
+}
+
+// region places the content of a region, according to the handout semantics
+// of the HTML generator: content for the slide show is omitted, notes for the
+// handout are marked with a vertical bar.
+func (pl *pdfLayout) region(args *sx.Pair) {
+	if val, found := zsx.GetAttributes(args.Car()).Get(""); found {
+		switch val {
+		case "show", "show-note", "only-show":
+			return
+		case "handout", "handout-note", "both", "note":
+			pl.withIndent(true, func() { pl.regionContent(args, false) })
+			return
+		}
+	}
+	pl.regionContent(args, false)
+}
+
+// regionContent places the blocks of a region, and optionally its
+// attribution.
+func (pl *pdfLayout) regionContent(args *sx.Pair, withCite bool) {
+	blocks := args.Tail()
+	if lst, isPair := sx.GetPair(blocks.Car()); isPair {
+		pl.blockList(lst)
+	}
+	if withCite {
+		var pi pdfInlines
+		pl.inlines(&pi, blocks.Tail(), pdfStyle{font: fontItalic, size: pdfFontSize})
+		if len(pi.boxes) > 0 {
+			pl.paragraph(&pi)
+			pl.skip(pdfParaSkip)
+		}
+	}
+}
+
+func (pl *pdfLayout) code(s string) {
+	st := pdfStyle{font: fontMono, size: pdfCodeSize}
+	h := pdfCodeSize * pdfLeading
+	maxChars := max(1, int((pdfRight-pl.left-4)/fontMono.Width(" ", pdfCodeSize)))
+	for line := range strings.SplitSeq(strings.ReplaceAll(s, "\t", "    "), "\n") {
+		enc := encodeWinAnsi(line)
+		for {
+			part := enc
+			if len(part) > maxChars {
+				part = enc[:maxChars]
+			}
+			pl.ensure(h)
+			pl.drawMarker()
+			pl.doc.Rect(pl.left, pl.y-h, pdfRight-pl.left, h, 0.95)
+			pl.doc.Text(pl.left+2, pl.y-pdfCodeSize, st.font, st.size, 0, part)
+			pl.drawBars(h)
+			pl.y -= h
+			if enc = enc[len(part):]; enc == "" {
+				break
+			}
+		}
+	}
+	pl.skip(pdfParaSkip)
+}
+
+// table places a table. All columns have the same width; the first row is
+// the header.
+func (pl *pdfLayout) table(args *sx.Pair) {
+	var rows [][]*sx.Pair
+	header := true
+	for obj := range args.Values() {
+		lst, isPair := sx.GetPair(obj)
+		if !isPair {
+			continue
+		}
+		var row []*sx.Pair
+		for cell := range lst.Values() {
+			if sym, cellArgs := getSymbolNode(cell); sym != nil && sym.IsEqualSymbol(zsx.SymCell) {
+				row = append(row, cellArgs)
+			}
+		}
+		if len(row) == 0 {
+			if len(rows) == 0 && lst == nil {
+				header = false
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	const gap = 8.0
+	colWidth := (pdfRight - pl.left - float64(cols-1)*gap) / float64(cols)
+	for rowNo, row := range rows {
+		st := pdfStyle{font: fontRegular, size: pdfFontSize}
+		if header && rowNo == 0 {
+			st = st.strong()
+		}
+		cellLines := make([][]*pdfLine, len(row))
+		rowHeight := 0.0
+		for i, cell := range row {
+			var pi pdfInlines
+			pl.inlines(&pi, cell, st)
+			cellLines[i] = breakLines(pi.boxes, colWidth)
+			h := 0.0
+			for _, ln := range cellLines[i] {
+				lh, _, _ := pl.lineHeight(ln, colWidth)
+				h += lh
+			}
+			rowHeight = max(rowHeight, h)
+		}
+		pl.ensure(rowHeight + 4)
+		top := pl.y
+		for i, lines := range cellLines {
+			pl.y = top
+			for _, ln := range lines {
+				pl.y -= pl.drawLine(ln, pl.left+float64(i)*(colWidth+gap), colWidth)
+			}
+		}
+		pl.y = top
+		pl.drawBars(rowHeight + 4)
+		pl.y -= rowHeight + 2
+		gray := 0.8
+		if header && rowNo == 0 {
+			gray = 0.3
+		}
+		pl.doc.Line(pl.left, pl.y, pdfRight, pl.y, 0.5, gray)
+		pl.y -= 2
+	}
+	pl.skip(pdfParaSkip)
+}
+
+// inlines collects the boxes of all inline nodes of a list.
+func (pl *pdfLayout) inlines(pi *pdfInlines, lst *sx.Pair, st pdfStyle) {
+	for obj := range lst.Values() {
+		sym, args := getSymbolNode(obj)
+		if sym == nil {
+			continue
+		}
+		switch {
+		case sym.IsEqualSymbol(zsx.SymText):
+			if s, isString := sx.GetString(args.Car()); isString {
+				pi.addText(s.GetValue(), st)
+			}
+		case sym.IsEqualSymbol(zsx.SymSoft):
+			pi.addSpace()
+		case sym.IsEqualSymbol(zsx.SymHard):
+			pi.addNewline()
+		case sym.IsEqualSymbol(zsx.SymLink):
+			numBoxes := len(pi.boxes)
+			pl.inlines(pi, args.Tail().Tail(), st)
+			if len(pi.boxes) == numBoxes {
+				_, ref := zsx.GetReference(args.Tail())
+				pi.addText(ref, st)
+			}
+		case sym.IsEqualSymbol(zsx.SymInline), sym.IsEqualSymbol(zsx.SymMark), sym.IsEqualSymbol(zsx.SymCite),
+			sym.IsEqualSymbol(zsx.SymFormatInsert), sym.IsEqualSymbol(zsx.SymFormatDelete),
+			sym.IsEqualSymbol(zsx.SymFormatMark), sym.IsEqualSymbol(zsx.SymFormatSpan):
+			pl.inlines(pi, args, st)
+		case sym.IsEqualSymbol(zsx.SymFormatEmph):
+			pl.inlines(pi, args, st.emph())
+		case sym.IsEqualSymbol(zsx.SymFormatStrong):
+			pl.inlines(pi, args, st.strong())
+		case sym.IsEqualSymbol(zsx.SymFormatSuper):
+			pl.inlines(pi, args, st.super())
+		case sym.IsEqualSymbol(zsx.SymFormatSub):
+			pl.inlines(pi, args, st.sub())
+		case sym.IsEqualSymbol(zsx.SymFormatQuote):
+			pi.addText("“", st)
+			pl.inlines(pi, args, st)
+			pi.addText("”", st)
+		case sym.IsEqualSymbol(zsx.SymLiteralCode), sym.IsEqualSymbol(zsx.SymLiteralInput),
+			sym.IsEqualSymbol(zsx.SymLiteralOutput), sym.IsEqualSymbol(zsx.SymLiteralMath):
+			pi.addText(getFirstString(args), st.mono())
+		case sym.IsEqualSymbol(zsx.SymEndnote):
+			pl.notes = append(pl.notes, args)
+			pi.addText(strconv.Itoa(len(pl.notes)), st.super())
+		case sym.IsEqualSymbol(zsx.SymEmbed):
+			pl.embed(pi, args, st)
+		}
+	}
+}
+
+// embed adds an image. If the image cannot be placed into the PDF, e.g.
+// because it is an SVG image, its description is shown instead.
+func (pl *pdfLayout) embed(pi *pdfInlines, args *sx.Pair, st pdfStyle) {
+	_, ref := zsx.GetReference(args.Tail())
+	if zid, err := id.Parse(ref); err == nil {
+		if img := pl.image(zid); img != nil {
+			pi.addImage(img)
+			return
+		}
+	}
+	st = st.emph()
+	pi.addText("[", st)
+	numBoxes := len(pi.boxes)
+	pl.inlines(pi, args.Tail().Tail().Tail(), st)
+	if len(pi.boxes) == numBoxes {
+		pi.addText("Image "+ref, st)
+	}
+	pi.addText("]", st)
+}
+
+func (pl *pdfLayout) image(zid id.Zid) *pdfImage {
+	if img, found := pl.images[zid]; found {
+		return img
+	}
+	var result *pdfImage
+	if img, found := pl.slides.GetImage(zid); found {
+		pdfImg, err := newPDFImage(img.data)
+		if err != nil {
+			slog.Debug("Unable to place image into PDF", "zid", zid, "syntax", img.syntax, "err", err)
+		} else {
+			result = pdfImg
+		}
+	}
+	pl.images[zid] = result
+	return result
+}
+
+// getFirstString returns the first string of a list, e.g. the text of a
+// verbatim node.
+func getFirstString(lst *sx.Pair) string {
+	for obj := range lst.Values() {
+		if s, isString := sx.GetString(obj); isString {
+			return s.GetValue()
+		}
+	}
+	return ""
+}
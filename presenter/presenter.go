@@ -21,11 +21,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"golang.org/x/term"
@@ -39,6 +41,7 @@ import (
 	"t73f.de/r/zsc/sz"
 	"t73f.de/r/zsc/text"
 	"t73f.de/r/zsc/webapi"
+	"zettelstore.de/contrib/server"
 )
 
 const langDE = "de"
@@ -57,7 +60,20 @@ func hasVersion(major, minor int) bool {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "pdf":
+			os.Exit(runPDF(os.Args[2:]))
+		}
+	}
 	listenAddress := flag.String("l", ":23120", "Listen address")
+	timeout := flag.Duration("t", 30*time.Second, "Timeout for retrieving data from Zettelstore")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn, error")
+	logFormat := flag.String("log-format", "text", "Log format: text, json")
 	flag.Usage = func() {
 		out := flag.CommandLine.Output()
 		_, _ = fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
@@ -65,7 +81,12 @@ func main() {
 		_, _ = io.WriteString(out, "  [URL] URL of Zettelstore (default: \"http://127.0.0.1:23123\")\n")
 	}
 	flag.Parse()
-	ctx := context.Background()
+	if err := server.SetupLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to configure logging: %v\n", err)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	c, err := getClient(ctx, flag.Arg(0))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to connect to zettelstore: %v\n", err)
@@ -77,10 +98,13 @@ func main() {
 		os.Exit(2)
 	}
 
-	http.HandleFunc("/", makeHandler(&cfg))
-	http.Handle("/revealjs/", http.FileServer(http.FS(revealjs)))
-	fmt.Println("Listening:", *listenAddress)
-	_ = http.ListenAndServe(*listenAddress, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", makeHandler(&cfg))
+	mux.Handle("/revealjs/", http.FileServer(http.FS(revealjs)))
+	if err = server.Serve(ctx, *listenAddress, server.WithTimeout(*timeout, mux)); err != nil {
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
 }
 
 func getClient(ctx context.Context, base string) (*client.Client, error) {
@@ -106,7 +130,7 @@ func getClient(ctx context.Context, base string) (*client.Client, error) {
 		return nil, err
 	}
 	if ver.Major == -1 {
-		fmt.Fprintln(os.Stderr, "Unknown zettelstore version. Use it at your own risk.")
+		slog.Warn("Unknown zettelstore version. Use it at your own risk.")
 	} else if !hasVersion(ver.Major, ver.Minor) {
 		return nil, fmt.Errorf("need at least zettelstore version %d.%d but found only %d.%d", minMajor, minMinor, ver.Major, ver.Minor)
 	}
@@ -191,6 +215,12 @@ func makeHandler(cfg *slidesConfig) http.HandlerFunc {
 				processSlideSet(w, r, cfg, zid, &revealRenderer{cfg: cfg})
 			case "html":
 				processSlideSet(w, r, cfg, zid, &handoutRenderer{cfg: cfg})
+			case "pdf":
+				processSlideSet(w, r, cfg, zid, &pdfRenderer{cfg: cfg})
+			case "tex":
+				processSlideSet(w, r, cfg, zid, &beamerRenderer{cfg: cfg, archive: true})
+			case "check":
+				processCheck(w, r, cfg, zid)
 			case "content":
 				if content := retrieveContent(w, r, cfg.c, zid); len(content) > 0 {
 					_, _ = w.Write(content)
@@ -209,7 +239,7 @@ func makeHandler(cfg *slidesConfig) http.HandlerFunc {
 			processList(w, r, cfg.c)
 			return
 		}
-		log.Println("NOTF", path)
+		slog.Info("Unhandled request", "path", path)
 		http.Error(w, fmt.Sprintf("Unhandled request %q", r.URL), http.StatusNotFound)
 	}
 }
@@ -272,7 +302,7 @@ func processZettel(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zi
 
 	role := sxMeta.GetString(meta.KeyRole)
 	if role == cfg.slideSetRole {
-		if slides := processSlideTOC(ctx, cfg.c, zid, sxMeta); slides != nil {
+		if slides := processSlideTOC(ctx, cfg.c, zid, sxMeta, sxContent); slides != nil {
 			renderSlideTOC(w, slides)
 			return
 		}
@@ -345,7 +375,7 @@ func getURLHtml(sxMeta sz.Meta) *sx.Pair {
 	return nil
 }
 
-func processSlideTOC(ctx context.Context, c *client.Client, zid id.Zid, sxMeta sz.Meta) *slideSet {
+func processSlideTOC(ctx context.Context, c *client.Client, zid id.Zid, sxMeta sz.Meta, sxContent *sx.Pair) *slideSet {
 	_, _, metaSeq, err := c.QueryZettelData(ctx, zid.String()+" "+webapi.ItemsDirective)
 	if err != nil {
 		return nil
@@ -355,7 +385,7 @@ func processSlideTOC(ctx context.Context, c *client.Client, zid id.Zid, sxMeta s
 	sGetZettel := func(zid id.Zid) (sx.Object, error) {
 		return c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
 	}
-	setupSlideSet(slides, metaSeq, getZettel, sGetZettel)
+	setupSlideSet(slides, sxContent, metaSeq, getZettel, sGetZettel)
 	return slides
 }
 
@@ -392,36 +422,77 @@ func renderSlideTOC(w http.ResponseWriter, slides *slideSet) {
 			getSimpleLink(fmt.Sprintf("/%s.slide#(%d)", slides.zid, si.Number), slideTitle)))
 	}
 	bodyHTML := sx.MakeList(shtml.SymBody, headerHTML, lstSlide)
+	if warningsHTML := getWarningsHTML(slides.Warnings()); warningsHTML != nil {
+		bodyHTML.LastPair().AppendBang(warningsHTML)
+	}
 	bodyHTML.LastPair().AppendBang(sx.MakeList(
 		shtml.SymP,
 		getSimpleLink("/"+slides.zid.String()+".reveal", sx.MakeList(sx.MakeString("Reveal"))),
 		sx.MakeString(", "),
 		getSimpleLink("/"+slides.zid.String()+".html", sx.MakeList(sx.MakeString("Handout"))),
+		sx.MakeString(", "),
+		getSimpleLink("/"+slides.zid.String()+".pdf", sx.MakeList(sx.MakeString("PDF"))),
+		sx.MakeString(", "),
+		getSimpleLink("/"+slides.zid.String()+".tex", sx.MakeList(sx.MakeString("Beamer"))),
 	))
 
 	gen.writeHTMLDocument(w, slides.Lang(), headHTML, bodyHTML)
 }
 
+// getWarningsHTML returns a summary of all zettel that could not be shown.
+func getWarningsHTML(warnings []slideWarning) *sx.Pair {
+	if len(warnings) == 0 {
+		return nil
+	}
+	lstWarnings := sx.MakeList(shtml.SymUL)
+	curr := lstWarnings
+	for _, w := range warnings {
+		curr = curr.AppendBang(sx.MakeList(shtml.SymLI, sx.MakeString(fmt.Sprintf("Zettel %s: %s", w.zid, w.errMsg))))
+	}
+	return sx.MakeList(
+		sxhtml.MakeSymbol("section"),
+		getClassAttr("warnings"),
+		sx.MakeList(shtml.SymH2, sx.MakeString("Warnings")),
+		lstWarnings,
+	)
+}
+
 func processSlideSet(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid id.Zid, ren renderer) {
 	ctx := r.Context()
-	_, _, metaSeq, err := cfg.c.QueryZettelData(ctx, zid.String()+" "+webapi.ItemsDirective)
+	slides, err := retrieveSlideSet(ctx, cfg.c, zid)
 	if err != nil {
-		reportRetrieveError(w, zid, err, "zettel")
+		reportRetrieveError(w, zid, err, "slide set")
 		return
 	}
-	sMeta, err := cfg.c.GetEvaluatedSz(ctx, zid, webapi.PartMeta)
+	ren.Prepare(ctx)
+	ren.Render(w, slides, slides.Author(cfg))
+}
+
+// errNoSlideSet is returned, if a zettel has no metadata of a slide set.
+var errNoSlideSet = errors.New("no slide set")
+
+// retrieveSlideSet retrieves the slide set zettel, all its slides, and all
+// additional content.
+func retrieveSlideSet(ctx context.Context, c *client.Client, zid id.Zid) (*slideSet, error) {
+	_, _, metaSeq, err := c.QueryZettelData(ctx, zid.String()+" "+webapi.ItemsDirective)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Unable to read zettel %s: %v", zid, err), http.StatusBadRequest)
-		return
+		return nil, err
 	}
-	slides := newSlideSet(zid, sz.MakeMeta(sMeta))
-	getZettel := func(zid id.Zid) ([]byte, error) { return cfg.c.GetZettel(ctx, zid, webapi.PartContent) }
+	sxZettel, err := c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
+	if err != nil {
+		return nil, err
+	}
+	sxMeta, sxContent := sz.GetMetaContent(sxZettel)
+	slides := newSlideSet(zid, sxMeta)
+	if slides == nil {
+		return nil, errNoSlideSet
+	}
+	getZettel := func(zid id.Zid) ([]byte, error) { return c.GetZettel(ctx, zid, webapi.PartContent) }
 	sGetZettel := func(zid id.Zid) (sx.Object, error) {
-		return cfg.c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
+		return c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
 	}
-	setupSlideSet(slides, metaSeq, getZettel, sGetZettel)
-	ren.Prepare(ctx)
-	ren.Render(w, slides, slides.Author(cfg))
+	setupSlideSet(slides, sxContent, metaSeq, getZettel, sGetZettel)
+	return slides, nil
 }
 
 type renderer interface {
@@ -433,6 +504,7 @@ type renderer interface {
 type revealRenderer struct {
 	cfg     *slidesConfig
 	userCSS string
+	export  bool // Render for a static export
 }
 
 func (*revealRenderer) Role() string { return SlideRoleShow }
@@ -445,6 +517,7 @@ func (rr *revealRenderer) Prepare(ctx context.Context) {
 }
 func (rr *revealRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
 	gen := newGenerator(slides, langDE, rr, true, false)
+	gen.SetExport(rr.export)
 
 	title := slides.Title()
 
@@ -528,18 +601,20 @@ func getRevealSlide(gen *htmlGenerator, si *slideInfo, lang string) *sx.Pair {
 	for content := range si.Slide.content.Pairs() {
 		curr = curr.AppendBang(gen.Transform(content.Head()))
 	}
-	curr.AppendBang(gen.Endnotes()).
-		AppendBang(sx.MakeList(
+	curr = curr.AppendBang(gen.Endnotes())
+	if ref := gen.ZettelRef(si.Slide.zid); ref != "" {
+		curr.AppendBang(sx.MakeList(
 			shtml.SymP,
 			sx.MakeList(
 				shtml.SymA,
 				sx.MakeList(
-					sx.Cons(shtml.SymAttrHref, sx.MakeString(si.Slide.zid.String())),
+					sx.Cons(shtml.SymAttrHref, sx.MakeString(ref)),
 					sx.Cons(shtml.SymAttrTarget, sx.MakeString("_blank")),
 				),
 				sx.MakeString("\u266e"),
 			),
 		))
+	}
 	return slideHTML
 }
 
@@ -550,12 +625,16 @@ func getJSFileScript(src string) *sx.Pair {
 	)
 }
 
-type handoutRenderer struct{ cfg *slidesConfig }
+type handoutRenderer struct {
+	cfg    *slidesConfig
+	export bool // Links and images refer to the files of a static export
+}
 
 func (*handoutRenderer) Role() string            { return SlideRoleHandout }
 func (*handoutRenderer) Prepare(context.Context) {}
 func (hr *handoutRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
 	gen := newGenerator(slides, langDE, hr, false, true)
+	gen.SetExport(hr.export)
 
 	handoutTitle := slides.Title()
 	copyright := slides.Copyright()
@@ -627,9 +706,9 @@ aside.handout { border: 0.2rem solid lightgray }
 }
 
 func getSlideNoRange(si *slideInfo) *sx.Pair {
-	if fromSlideNo := si.SlideNo; fromSlideNo > 0 {
+	if fromSlideNo, toSlideNo := slideNoRange(si); fromSlideNo > 0 {
 		lstSlNo := sx.MakeList(sxhtml.SymNoEscape)
-		if toSlideNo := si.LastChild().SlideNo; fromSlideNo < toSlideNo {
+		if fromSlideNo < toSlideNo {
 			lstSlNo.AppendBang(sx.MakeString(fmt.Sprintf(" (S.%d&ndash;%d)", fromSlideNo, toSlideNo)))
 		} else {
 			lstSlNo.AppendBang(sx.MakeString(fmt.Sprintf(" (S.%d)", fromSlideNo)))
@@ -639,9 +718,48 @@ func getSlideNoRange(si *slideInfo) *sx.Pair {
 	return nil
 }
 
-func setupSlideSet(slides *slideSet, l []webapi.ZidMetaRights, getZettel getZettelContentFunc, sGetZettel sGetZettelFunc) {
+// slideNoRange returns the numbers of the first and the last slide of the
+// slide show that show the given slide. Both are zero, if the slide is not
+// shown.
+func slideNoRange(si *slideInfo) (int, int) {
+	if fromSlideNo := si.SlideNo; fromSlideNo > 0 {
+		return fromSlideNo, si.LastChild().SlideNo
+	}
+	return 0, 0
+}
+
+// setupSlideSet adds all slides of the slide set, in the order of the list l.
+// References of the slide set content to zettel that are not part of l are
+// missing or not readable; they result in error slides, placed after the
+// slide that precedes them within the content.
+func setupSlideSet(slides *slideSet, sxContent *sx.Pair, l []webapi.ZidMetaRights, getZettel getZettelContentFunc, sGetZettel sGetZettelFunc) {
+	items := make(map[id.Zid]bool, len(l))
+	for _, sl := range l {
+		items[sl.ID] = true
+	}
+	anchor := id.Invalid
+	missing := map[id.Zid][]slideRef{}
+	for _, ref := range getSlideRefs(sxContent) {
+		if items[ref.zid] {
+			anchor = ref.zid
+		} else {
+			missing[anchor] = append(missing[anchor], ref)
+		}
+	}
+	addErrorSlides := func(anchor id.Zid) {
+		for _, ref := range missing[anchor] {
+			if ref.broken {
+				slides.AddErrorSlide(ref.zid, errMsgNotFound)
+			} else {
+				slides.AddErrorSlide(ref.zid, errMsgForbidden)
+			}
+		}
+		delete(missing, anchor)
+	}
+	addErrorSlides(id.Invalid)
 	for _, sl := range l {
 		slides.AddSlide(sl.ID, sGetZettel)
+		addErrorSlides(sl.ID)
 	}
 	slides.Completion(getZettel, sGetZettel)
 }
@@ -713,6 +831,9 @@ var defaultCSS = []string{
 	"a.broken { text-decoration: line-through }",
 	".reveal blockquote { font-style: normal }",
 	"p.updated { font-size: smaller }",
+	"section.warnings { border-left: 4px solid #c00; padding-left: 1em }",
+	"li.check-error strong { color: #c00 }",
+	"li.check-warning strong { color: #b60 }",
 }
 
 func getPrefixedCSS(extraCSS string) *sx.Pair {
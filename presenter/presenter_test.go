@@ -0,0 +1,764 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Presenter.
+//
+// Zettel Presenter is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/zsc/domain/id"
+	"t73f.de/r/zsc/sz"
+	"t73f.de/r/zsc/webapi"
+	"t73f.de/r/zsx"
+	"zettelstore.de/contrib/zstest"
+)
+
+// Identifier of test zettel.
+const (
+	zidTestSlideSet    = "20260101000000"
+	zidTestIntro       = "20260101000100"
+	zidTestDiagram     = "20260101000200"
+	zidTestLinks       = "20260101000300"
+	zidTestMore        = "20260101000400"
+	zidTestSecret      = "20260101000500"
+	zidTestHandout     = "20260101000600"
+	zidTestImage       = "20260101000900"
+	zidTestPresenter   = "20260101001000"
+	zidTestNonExisting = "20269999000000"
+)
+
+// testZettel returns a slide set with four slides. The first slide is split
+// into three sub-slides, the second embeds an image, the third links to a
+// public and to a non-public zettel, and the fourth is shown in the handout
+// only.
+func testZettel() []*zstest.Zettel {
+	return []*zstest.Zettel{
+		zstest.NewZettel(zidTestSlideSet,
+			"* [[Introduction|"+zidTestIntro+"]]\n* [["+zidTestDiagram+"]]\n* [["+zidTestLinks+"]]\n* [["+zidTestHandout+"]]\n",
+			"title", "Test Talk",
+			"sub-title", "Testing the presenter",
+			"role", "slideset",
+			"author", "Ada",
+			"copyright", "2026 Ada",
+			"license", "CC0",
+			"published", "20260101120000",
+			"lang", "en",
+		),
+		zstest.NewZettel(zidTestIntro,
+			"Opening words\n\n=== Details\nSome details[^An endnote]\n\n---{-}\nContinued\n\n:::show\nSpeaker note\n\n```\nx = 1 & 2\n```\n:::\n\n:::handout\nHandout note\n:::\n",
+			"title", "Introduction", "role", "slide"),
+		zstest.NewZettel(zidTestDiagram, "{{Diagram|"+zidTestImage+"}}", "title", "Diagram", "role", "slide"),
+		zstest.NewZettel(zidTestLinks,
+			"See [[more|"+zidTestMore+"]] and [[secret|"+zidTestSecret+"]].",
+			"title", "Links", "role", "slide"),
+		zstest.NewZettel(zidTestMore, "Additional content", "title", "More", "role", "zettel", "visibility", "public"),
+		zstest.NewZettel(zidTestSecret, "Secret content", "title", "Secret", "role", "zettel"),
+		zstest.NewZettel(zidTestHandout, "Only in the handout", "title", "Handout only", "role", "slide", "slide-role", "handout"),
+		zstest.NewZettel(zidTestImage, "\x89PNG image data", "title", "Image", "role", "image", "syntax", "png"),
+	}
+}
+
+// startPresenter starts a presenter for a fake Zettelstore with the test
+// zettel. It returns the configuration and the server.
+func startPresenter(t testing.TB, store *zstest.Server) (*slidesConfig, *httptest.Server) {
+	t.Helper()
+	zsSrv := httptest.NewServer(store)
+	t.Cleanup(zsSrv.Close)
+
+	ctx := context.Background()
+	c, err := getClient(ctx, zsSrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := getConfig(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", makeHandler(&cfg))
+	mux.Handle("/revealjs/", http.FileServer(http.FS(revealjs)))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &cfg, srv
+}
+
+func getPage(t *testing.T, srv *httptest.Server, path string) (int, string) {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+// loadSlideSet retrieves a slide set like processSlideSet does.
+func loadSlideSet(t testing.TB, cfg *slidesConfig, strZid string) *slideSet {
+	t.Helper()
+	ctx := context.Background()
+	zid, err := id.Parse(strZid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, metaSeq, err := cfg.c.QueryZettelData(ctx, zid.String()+" "+webapi.ItemsDirective)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sxZettel, err := cfg.c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sxMeta, sxContent := sz.GetMetaContent(sxZettel)
+	slides := newSlideSet(zid, sxMeta)
+	if slides == nil {
+		t.Fatal("no slide set metadata")
+	}
+	getZettel := func(zid id.Zid) ([]byte, error) { return cfg.c.GetZettel(ctx, zid, webapi.PartContent) }
+	sGetZettel := func(zid id.Zid) (sx.Object, error) {
+		return cfg.c.GetEvaluatedSz(ctx, zid, webapi.PartZettel)
+	}
+	setupSlideSet(slides, sxContent, metaSeq, getZettel, sGetZettel)
+	return slides
+}
+
+func zidStrings(zids []id.Zid) []string {
+	result := make([]string, len(zids))
+	for i, zid := range zids {
+		result[i] = zid.String()
+	}
+	return result
+}
+
+func TestRetrieveZidAndSuffix(t *testing.T) {
+	testcases := []struct {
+		path   string
+		zid    id.Zid
+		suffix string
+	}{
+		{"", id.Invalid, ""},
+		{"/", id.ZidDefaultHome, ""},
+		{"/20260101000000", 20260101000000, ""},
+		{"/20260101000000.reveal", 20260101000000, "reveal"},
+		{"/20260101000000.", id.Invalid, ""},
+		{"/20260101000000x", id.Invalid, ""},
+		{"/2026", id.Invalid, ""},
+	}
+	for _, tc := range testcases {
+		if zid, suffix := retrieveZidAndSuffix(tc.path); zid != tc.zid || suffix != tc.suffix {
+			t.Errorf("%q: expected %v/%q, but got %v/%q", tc.path, tc.zid, tc.suffix, zid, suffix)
+		}
+	}
+}
+
+func TestSlideSplitting(t *testing.T) {
+	cfg, _ := startPresenter(t, zstest.NewServer(testZettel()...))
+	slides := loadSlideSet(t, cfg, zidTestSlideSet)
+
+	expZids := []string{zidTestIntro, zidTestDiagram, zidTestLinks, zidTestHandout, zidTestMore}
+	if got := zidStrings(slides.SlideZids()); !slices.Equal(got, expZids) {
+		t.Errorf("expected slides %v, but got %v", expZids, got)
+	}
+	if got := zidStrings(slides.Images()); !slices.Equal(got, []string{zidTestImage}) {
+		t.Errorf("expected image %v, but got %v", zidTestImage, got)
+	}
+
+	// Slide numbers of the show: the title slide is number 1, the first
+	// slide is split into three sub-slides, the handout slide is not shown.
+	var got []int
+	for si := slides.Slides(SlideRoleShow, 2); si != nil; si = si.Next() {
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			got = append(got, sub.SlideNo)
+		}
+	}
+	if exp := []int{2, 3, 4, 5, 6, 7}; !slices.Equal(got, exp) {
+		t.Errorf("expected show slide numbers %v, but got %v", exp, got)
+	}
+
+	si := slides.Slides(SlideRoleShow, 2)
+	var titles []string
+	for sub := si.Child(); sub != nil; sub = sub.Next() {
+		if sub.Slide.title == nil {
+			titles = append(titles, "")
+		} else {
+			titles = append(titles, sub.Slide.title.String())
+		}
+	}
+	if len(titles) != 3 || !strings.Contains(titles[0], "Introduction") || !strings.Contains(titles[1], "Details") || titles[2] != "" {
+		t.Errorf("unexpected titles of sub-slides: %v", titles)
+	}
+
+	got = nil
+	for si = slides.Slides(SlideRoleHandout, 2); si != nil; si = si.Next() {
+		got = append(got, si.Number)
+	}
+	if exp := []int{2, 3, 4, 5, 6}; !slices.Equal(got, exp) {
+		t.Errorf("expected handout numbers %v, but got %v", exp, got)
+	}
+}
+
+func TestRevealRenderer(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	status, body := getPage(t, srv, "/"+zidTestSlideSet+".reveal")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", status)
+	}
+	for _, s := range []string{
+		"Test Talk", "Testing the presenter", "Ada",
+		`id="(2)"`, `id="(4)"`, `id="(7)"`,
+		"Speaker note", "An endnote", "Additional content",
+		"/" + zidTestImage + ".content", "revealjs/reveal.js",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("reveal slides do not contain %q", s)
+		}
+	}
+	for _, s := range []string{"Handout note", "Only in the handout", "Secret content"} {
+		if strings.Contains(body, s) {
+			t.Errorf("reveal slides must not contain %q", s)
+		}
+	}
+	if status, _ = getPage(t, srv, "/revealjs/reveal.js"); status != http.StatusOK {
+		t.Errorf("reveal.js not served: %d", status)
+	}
+}
+
+func TestHandoutRenderer(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	status, body := getPage(t, srv, "/"+zidTestSlideSet+".html")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", status)
+	}
+	for _, s := range []string{
+		"Test Talk", "Ada", "2026 Ada", "CC0", "Update: 2026-01-01 12:00",
+		"Handout note", "Only in the handout", "An endnote", "Additional content",
+		"(S.2&ndash;4)", "data:image/png;base64,",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("handout does not contain %q", s)
+		}
+	}
+	for _, s := range []string{"Speaker note", "Secret content", "/" + zidTestSecret} {
+		if strings.Contains(body, s) {
+			t.Errorf("handout must not contain %q", s)
+		}
+	}
+}
+
+func TestSlideTOC(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	status, body := getPage(t, srv, "/"+zidTestSlideSet)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d", status)
+	}
+	for _, s := range []string{
+		"/" + zidTestSlideSet + ".slide#(1)", "/" + zidTestSlideSet + ".slide#(2)", "/" + zidTestSlideSet + ".slide#(7)",
+		"/" + zidTestSlideSet + ".reveal", "/" + zidTestSlideSet + ".html",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("table of contents does not contain %q", s)
+		}
+	}
+}
+
+func TestZettelAndContent(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	testcases := []struct {
+		path   string
+		status int
+		text   string
+	}{
+		{"/" + zidTestMore, http.StatusOK, "Additional content"},
+		{"/" + zidTestImage + ".content", http.StatusOK, "\x89PNG image data"},
+		{"/" + zidTestNonExisting, http.StatusNotFound, ""},
+		{"/" + zidTestNonExisting + ".reveal", http.StatusNotFound, ""},
+		{"/l?q=role%3Dslide", http.StatusOK, "Handout only"},
+		{"/unknown/path", http.StatusNotFound, ""},
+	}
+	for _, tc := range testcases {
+		status, body := getPage(t, srv, tc.path)
+		if status != tc.status {
+			t.Errorf("%s: expected status %d, but got %d", tc.path, tc.status, status)
+			continue
+		}
+		if !strings.Contains(body, tc.text) {
+			t.Errorf("%s: %q not found in %q", tc.path, tc.text, body)
+		}
+	}
+}
+
+func TestApplicationConfig(t *testing.T) {
+	zs := append(testZettel(), zstest.NewZettel(zidTestPresenter, "",
+		"title", "Presenter configuration",
+		"role", "configuration",
+		"slideset-role", "talk",
+		"author", "Configured Author",
+		"css-zid", zidTestMore,
+	))
+	store := zstest.NewServer(zs...)
+	store.SetApplication("zettel-presenter", zidTestPresenter)
+	cfg, _ := startPresenter(t, store)
+	if cfg.slideSetRole != "talk" || cfg.author != "Configured Author" || cfg.slideCSS.String() != zidTestMore {
+		t.Errorf("unexpected configuration %q %q %v", cfg.slideSetRole, cfg.author, cfg.slideCSS)
+	}
+}
+
+func TestAuthenticatedClient(t *testing.T) {
+	store := zstest.NewServer(testZettel()...)
+	store.AddUser("speaker", "secret")
+	zsSrv := httptest.NewServer(store)
+	defer zsSrv.Close()
+
+	ctx := context.Background()
+	c, err := getClient(ctx, strings.Replace(zsSrv.URL, "://", "://speaker:secret@", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Requests()["auth"]; got != 1 {
+		t.Errorf("expected one authentication, but got %d", got)
+	}
+	if _, err = c.GetZettel(ctx, id.Zid(20260101000500), webapi.PartContent); err != nil {
+		t.Errorf("authenticated client cannot read non-public zettel: %v", err)
+	}
+}
+
+func TestErrorSlides(t *testing.T) {
+	zs := testZettel()
+	for _, z := range zs {
+		if z.ID != zidTestSecret {
+			z.Meta["visibility"] = "public"
+		}
+	}
+	zs[0].Content = "* [[" + zidTestIntro + "]]\n* [[Missing|" + zidTestNonExisting + "]]\n* [[" + zidTestSecret + "]]\n* [[" + zidTestLinks + "]]\n"
+	store := zstest.NewServer(zs...)
+	store.AddUser("owner", "secret")
+	cfg, srv := startPresenter(t, store)
+
+	slides := loadSlideSet(t, cfg, zidTestSlideSet)
+	expZids := []string{zidTestIntro, zidTestNonExisting, zidTestSecret, zidTestLinks, zidTestMore}
+	if got := zidStrings(slides.SlideZids()); !slices.Equal(got, expZids) {
+		t.Errorf("expected slides %v, but got %v", expZids, got)
+	}
+	expWarnings := []slideWarning{
+		{zid: id.Zid(20269999000000), errMsg: errMsgNotFound},
+		{zid: id.Zid(20260101000500), errMsg: errMsgForbidden},
+	}
+	if got := slides.Warnings(); !slices.Equal(got, expWarnings) {
+		t.Errorf("expected warnings %v, but got %v", expWarnings, got)
+	}
+
+	testcases := []struct {
+		path string
+		text []string
+	}{
+		{"/" + zidTestSlideSet + ".reveal", []string{
+			"Error: zettel " + zidTestNonExisting + " not found",
+			"Zettel " + zidTestSecret + " cannot be shown: not readable.",
+		}},
+		{"/" + zidTestSlideSet + ".html", []string{
+			"Error: zettel " + zidTestNonExisting + " not found",
+			"Zettel " + zidTestSecret + " cannot be shown: not readable.",
+		}},
+		{"/" + zidTestSlideSet, []string{
+			"Error: zettel " + zidTestSecret + " not readable",
+			"Warnings",
+			"Zettel " + zidTestNonExisting + ": not found",
+		}},
+	}
+	for _, tc := range testcases {
+		status, body := getPage(t, srv, tc.path)
+		if status != http.StatusOK {
+			t.Errorf("%s: expected status 200, but got %d", tc.path, status)
+			continue
+		}
+		for _, s := range tc.text {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s does not contain %q", tc.path, s)
+			}
+		}
+	}
+}
+
+func TestSlideOrder(t *testing.T) {
+	zs := testZettel()
+	zs[0].Content = "* [[" + zidTestIntro + "]]\n\nText between the lists.\n\n# [[Missing|" + zidTestNonExisting + "]]\n# [[" + zidTestLinks + "]]\n"
+	cfg, _ := startPresenter(t, zstest.NewServer(zs...))
+
+	slides := loadSlideSet(t, cfg, zidTestSlideSet)
+	expZids := []string{zidTestIntro, zidTestNonExisting, zidTestLinks, zidTestMore}
+	if got := zidStrings(slides.SlideZids()); !slices.Equal(got, expZids) {
+		t.Errorf("expected slides %v, but got %v", expZids, got)
+	}
+}
+
+func TestSlideRefsNested(t *testing.T) {
+	link := func(refSym *sx.Symbol, zid string) sx.Object {
+		return sx.MakeList(zsx.SymPara, sx.MakeList(zsx.SymLink, sx.Nil(), sx.MakeList(refSym, sx.MakeString(zid))))
+	}
+	list := func(items ...sx.Object) *sx.Pair {
+		return sx.MakeList(append([]sx.Object{zsx.SymListUnordered, sx.Nil()}, items...)...)
+	}
+	// * [[Intro]]
+	// ** [[Missing]]
+	// * Text
+	// ** [[More]]
+	content := sx.MakeList(list(
+		sx.MakeList(link(sz.SymRefStateZettel, zidTestIntro), list(sx.MakeList(link(sz.SymRefStateBroken, zidTestNonExisting)))),
+		sx.MakeList(sx.MakeList(zsx.SymPara, sx.MakeString("Text")), list(sx.MakeList(link(sz.SymRefStateZettel, zidTestMore)))),
+	))
+	var got []string
+	for _, ref := range getSlideRefs(content) {
+		got = append(got, ref.zid.String())
+	}
+	if exp := []string{zidTestIntro, zidTestNonExisting, zidTestMore}; !slices.Equal(got, exp) {
+		t.Errorf("expected slide references %v, but got %v", exp, got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const (
+		zidDraft   = "20260101000700"
+		zidHeading = "20260101000800"
+		zidPhoto   = "20260101001100"
+		zidWebp    = "20260101001200"
+	)
+	zs := append(testZettel(),
+		zstest.NewZettel(zidDraft, "Draft", "title", "Draft", "role", "slide", "slide-role", "draft"),
+		zstest.NewZettel(zidHeading, "=== Only heading\nText", "title", "Heading", "role", "slide"),
+		zstest.NewZettel(zidPhoto, "{{Photo|"+zidWebp+"}}", "title", "Photo", "role", "slide"),
+		zstest.NewZettel(zidWebp, "RIFF image data", "title", "Webp", "role", "image", "syntax", "webp"),
+	)
+	zs[0].Content = "* [[" + zidTestIntro + "]]\n* [[" + zidTestNonExisting + "]]\n* [[" + zidDraft + "]]\n" +
+		"* [[" + zidHeading + "]]\n* [[" + zidPhoto + "]]\n* [[" + zidTestLinks + "]]\n* [[" + zidTestIntro + "]]\n"
+	_, srv := startPresenter(t, zstest.NewServer(zs...))
+
+	status, body := getPage(t, srv, "/"+zidTestSlideSet+".check?enc=json")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, but got %d: %s", status, body)
+	}
+	var cr checkReport
+	if err := json.Unmarshal([]byte(body), &cr); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range cr.Findings {
+		got = append(got, f.Kind+" "+f.Zid)
+	}
+	exp := []string{
+		findingMissing + " " + zidTestNonExisting,
+		findingNonPublic + " " + zidTestSecret,
+		findingImageSyntax + " " + zidWebp,
+		findingSlideRole + " " + zidDraft,
+		findingEmptySlide + " " + zidHeading,
+		findingDuplicate + " " + zidTestIntro,
+	}
+	if !slices.Equal(got, exp) {
+		t.Errorf("expected findings\n%v\nbut got\n%v", exp, got)
+	}
+	if cr.Title != "Test Talk" || !cr.HasErrors() {
+		t.Errorf("unexpected report %q %v", cr.Title, cr.HasErrors())
+	}
+
+	status, body = getPage(t, srv, "/"+zidTestSlideSet+".check")
+	if status != http.StatusOK || !strings.Contains(body, "Check: Test Talk") || !strings.Contains(body, `class="check-error"`) {
+		t.Errorf("unexpected HTML report %d: %s", status, body)
+	}
+}
+
+func TestExport(t *testing.T) {
+	cfg, _ := startPresenter(t, zstest.NewServer(testZettel()...))
+	slides := loadSlideSet(t, cfg, zidTestSlideSet)
+	path := filepath.Join(t.TempDir(), "talk.zip")
+	target, err := newExportTarget(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = exportSlideSet(context.Background(), cfg, slides, target); err != nil {
+		t.Fatal(err)
+	}
+	if err = target.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = zr.Close() }()
+	files := make(map[string]string, len(zr.File))
+	for _, f := range zr.File {
+		rc, errOpen := f.Open()
+		if errOpen != nil {
+			t.Fatal(errOpen)
+		}
+		data, errRead := io.ReadAll(rc)
+		_ = rc.Close()
+		if errRead != nil {
+			t.Fatal(errRead)
+		}
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{
+		exportIndexFile, exportHandoutFile, zidTestMore + ".html", zidTestImage + ".png",
+		"revealjs/reveal.js", "revealjs/reveal.css",
+	} {
+		if _, found := files[name]; !found {
+			t.Errorf("export does not contain file %q", name)
+		}
+	}
+	if files[zidTestImage+".png"] != "\x89PNG image data" {
+		t.Errorf("unexpected image data %q", files[zidTestImage+".png"])
+	}
+	if _, found := files[zidTestSecret+".html"]; found {
+		t.Errorf("export must not contain non-public zettel %s", zidTestSecret)
+	}
+
+	index := files[exportIndexFile]
+	for _, s := range []string{
+		`src="` + zidTestImage + `.png"`, `href="` + zidTestMore + `.html"`, `"revealjs/reveal.js"`,
+	} {
+		if !strings.Contains(index, s) {
+			t.Errorf("exported slides do not contain %q", s)
+		}
+	}
+	for _, s := range []string{`="/`, ".content", `href="` + zidTestIntro + `"`} {
+		if strings.Contains(index, s) {
+			t.Errorf("exported slides must not contain %q", s)
+		}
+	}
+	handout := files[exportHandoutFile]
+	for _, s := range []string{`src="/`, ".content"} {
+		if strings.Contains(handout, s) {
+			t.Errorf("exported handout must not contain %q", s)
+		}
+	}
+	if more := files[zidTestMore+".html"]; !strings.Contains(more, "Additional content") || !strings.Contains(more, exportIndexFile) {
+		t.Errorf("unexpected additional zettel page: %s", more)
+	}
+}
+
+// pdfContent returns the uncompressed content of all streams of a PDF document
+// that contain text.
+func pdfContent(t *testing.T, data string) string {
+	t.Helper()
+	var sb strings.Builder
+	re := regexp.MustCompile(`/Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+	for _, loc := range re.FindAllStringSubmatchIndex(data, -1) {
+		n, err := strconv.Atoi(data[loc[2]:loc[3]])
+		if err != nil || loc[1]+n > len(data) {
+			t.Fatalf("invalid stream length %q", data[loc[2]:loc[3]])
+		}
+		zr, err := zlib.NewReader(strings.NewReader(data[loc[1] : loc[1]+n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(content, []byte(" Tj ET")) {
+			sb.Write(content)
+		}
+	}
+	return sb.String()
+}
+
+func TestPDFHandout(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	resp, err := srv.Client().Get(srv.URL + "/" + zidTestSlideSet + ".pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(body, "%PDF-1.4\n") || !strings.HasSuffix(body, "%%EOF\n") {
+		t.Fatalf("not a PDF document: %q", body[:min(len(body), 40)])
+	}
+	for _, s := range []string{"/Title (Test Talk)", "/Subject (Testing the presenter)", "/Author (Ada)", "/License (CC0)", "/Count 2 "} {
+		if !strings.Contains(body, s) {
+			t.Errorf("PDF does not contain %q", s)
+		}
+	}
+
+	content := pdfContent(t, body)
+	for _, s := range []string{
+		"(Test) Tj", "(2026) Tj", "(Update:) Tj", "(Introduction) Tj", "(\\(S.2\x964\\)) Tj", "(Handout) Tj", "(endnote) Tj",
+		"(Only) Tj", "(Additional) Tj", "([) Tj", "(Diagram) Tj", "(1 / 2) Tj",
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("PDF content does not contain %q", s)
+		}
+	}
+	for _, s := range []string{"(Speaker) Tj", "(Secret) Tj"} {
+		if strings.Contains(content, s) {
+			t.Errorf("PDF content must not contain %q", s)
+		}
+	}
+}
+
+func TestBeamerRenderer(t *testing.T) {
+	_, srv := startPresenter(t, zstest.NewServer(testZettel()...))
+	resp, err := srv.Client().Get(srv.URL + "/" + zidTestSlideSet + ".tex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if exp := []string{exportBeamerFile, zidTestImage + ".png"}; !slices.Equal(names, exp) {
+		t.Errorf("expected files %v, but got %v", exp, names)
+	}
+	f, err := zr.Open(exportBeamerFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	data, err = io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	for _, s := range []string{
+		"\\documentclass{beamer}", "\\usepackage[english]{babel}", "\\title{Test Talk}",
+		"\\subtitle{Testing the presenter}", "\\author{Ada}", "\\titlepage",
+		"\\begin{frame}[label=slide2]\n\\frametitle{Introduction}", "\\frametitle{Details}",
+		"\\footnote{An endnote}", "\\note{Speaker note\n", "{\\ttfamily\n\\mbox{}x~=~1~\\&~2\\par}",
+		"{" + zidTestImage + ".png}",
+		"\\hyperlink{slide", "}{more}", "\\end{document}",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("Beamer document does not contain %q", s)
+		}
+	}
+	for _, s := range []string{"Handout note", "Only in the handout", "Secret content", "\\hyperlink{slide0}", "\\begin{verbatim}"} {
+		if strings.Contains(body, s) {
+			t.Errorf("Beamer document must not contain %q", s)
+		}
+	}
+	if n := strings.Count(body, "\\begin{frame}"); n != strings.Count(body, "\\end{frame}") {
+		t.Errorf("unbalanced frames in %s", body)
+	}
+}
+
+func TestTexEscape(t *testing.T) {
+	testcases := []struct {
+		s   string
+		exp string
+	}{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"50% & $5 #1 a_b {x}", "50\\% \\& \\$5 \\#1 a\\_b \\{x\\}"},
+		{"a\\b ~ ^", "a\\textbackslash{}b \\textasciitilde{} \\textasciicircum{}"},
+		{"Äpfel <3>", "Äpfel \\textless{}3\\textgreater{}"},
+	}
+	for _, tc := range testcases {
+		if got := texEscape(tc.s); got != tc.exp {
+			t.Errorf("texEscape(%q) == %q, but got %q", tc.s, tc.exp, got)
+		}
+	}
+}
+
+func TestExportBeamer(t *testing.T) {
+	cfg, _ := startPresenter(t, zstest.NewServer(testZettel()...))
+	slides := loadSlideSet(t, cfg, zidTestSlideSet)
+	dir := t.TempDir()
+	target, err := newExportTarget(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = exportBeamer(context.Background(), cfg, slides, target); err != nil {
+		t.Fatal(err)
+	}
+	if err = target.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if exp := []string{zidTestImage + ".png", exportBeamerFile}; !slices.Equal(names, exp) {
+		t.Errorf("expected files %v, but got %v", exp, names)
+	}
+}
+
+func TestBreakLines(t *testing.T) {
+	st := pdfStyle{font: fontRegular, size: pdfFontSize}
+	var pi pdfInlines
+	pi.addText("A paragraph with a ", st)
+	pi.addText("strong", st.strong())
+	pi.addText("ly formatted word and an extraordinarilylongwordthatmustbesplit.", st)
+	pi.addNewline()
+	pi.addText("Last line", st)
+	var got []string
+	for _, ln := range breakLines(pi.boxes, 100) {
+		if ln.width > 100 {
+			t.Errorf("line is too wide: %v", ln.width)
+		}
+		var sb strings.Builder
+		for i, item := range ln.items {
+			if i > 0 && item.x > ln.items[i-1].x+ln.items[i-1].piece.width {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(item.piece.text)
+		}
+		got = append(got, sb.String())
+	}
+	exp := []string{
+		"A paragraph with a", "strongly formatted", "word and an", "extraordinarilylongw", "ordthatmustbesplit.", "Last line",
+	}
+	if !slices.Equal(got, exp) {
+		t.Errorf("expected lines\n%q\nbut got\n%q", exp, got)
+	}
+}
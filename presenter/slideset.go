@@ -14,10 +14,15 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
 	"time"
 
 	"t73f.de/r/sx"
+	"t73f.de/r/zsc/client"
 	"t73f.de/r/zsc/domain/id"
 	"t73f.de/r/zsc/domain/meta"
 	"t73f.de/r/zsc/sz"
@@ -49,6 +54,7 @@ type slide struct {
 	role    string
 	ts      time.Time
 	content *sx.Pair // Zettel / slide content
+	errMsg  string   // Error message, if the zettel could not be shown
 }
 
 func newSlide(zid id.Zid, sxMeta sz.Meta, sxContent *sx.Pair) *slide {
@@ -65,6 +71,22 @@ func newSlide(zid id.Zid, sxMeta sz.Meta, sxContent *sx.Pair) *slide {
 		content: sxContent,
 	}
 }
+
+// newErrorSlide creates an artificial slide that names the zettel and the
+// reason why it could not be shown.
+func newErrorSlide(zid id.Zid, errMsg string) *slide {
+	text := fmt.Sprintf("Zettel %s cannot be shown: %s.", zid, errMsg)
+	return &slide{
+		zid:   zid,
+		title: makeTitleList(fmt.Sprintf("Error: zettel %s %s", zid, errMsg)),
+		content: sx.MakeList(
+			zsx.SymBlock,
+			sx.MakeList(zsx.SymPara, sx.MakeList(zsx.SymText, sx.MakeString(text))),
+		),
+		errMsg: errMsg,
+	}
+}
+
 func (sl *slide) MakeChild(sxTitle, sxContent *sx.Pair) *slide {
 	return &slide{
 		zid:     sl.zid,
@@ -73,6 +95,7 @@ func (sl *slide) MakeChild(sxTitle, sxContent *sx.Pair) *slide {
 		role:    sl.role,
 		ts:      sl.ts,
 		content: sxContent,
+		errMsg:  sl.errMsg,
 	}
 }
 
@@ -229,9 +252,40 @@ type slideSet struct {
 	seqSlide    []*slide // slide may occur more than once in seq, but should be stored only once
 	setSlide    map[id.Zid]*slide
 	setImage    map[id.Zid]image
+	warnings    []slideWarning
+	nonPublic   []id.Zid // Linked zettel that are not shown
+	additional  []id.Zid // Linked public zettel that are added as slides
 	isCompleted bool
 }
 
+// slideWarning describes a zettel of the slide set that could not be shown.
+type slideWarning struct {
+	zid    id.Zid
+	errMsg string
+}
+
+// Error messages for zettel that could not be shown.
+const (
+	errMsgNotFound  = "not found"
+	errMsgForbidden = "not readable"
+	errMsgParse     = "unable to parse zettel"
+)
+
+// errorMessage returns a short description of an error when retrieving a
+// zettel.
+func errorMessage(err error) string {
+	var cerr *client.Error
+	if errors.As(err, &cerr) {
+		switch cerr.StatusCode {
+		case http.StatusNotFound:
+			return errMsgNotFound
+		case http.StatusForbidden, http.StatusUnauthorized:
+			return errMsgForbidden
+		}
+	}
+	return err.Error()
+}
+
 func newSlideSet(zid id.Zid, sxMeta sz.Meta) *slideSet {
 	if len(sxMeta) == 0 {
 		return nil
@@ -404,12 +458,14 @@ func (s *slideSet) AddSlide(zid id.Zid, sGetZettel sGetZettelFunc) {
 
 	sxZettel, err := sGetZettel(zid)
 	if err != nil {
-		// TODO: add artificial slide with error message / data
+		slog.Warn("Unable to retrieve slide", "zid", zid, "err", err)
+		s.AddErrorSlide(zid, errorMessage(err))
 		return
 	}
 	sxMeta, sxContent := sz.GetMetaContent(sxZettel)
 	if sxMeta == nil || sxContent == nil {
-		// TODO: Add artificial slide with error message
+		slog.Warn("Slide without metadata or content", "zid", zid)
+		s.AddErrorSlide(zid, errMsgParse)
 		return
 	}
 	sl := newSlide(zid, sxMeta, sxContent)
@@ -417,13 +473,45 @@ func (s *slideSet) AddSlide(zid id.Zid, sGetZettel sGetZettelFunc) {
 	s.setSlide[zid] = sl
 }
 
+// AddErrorSlide adds an artificial slide for a zettel that could not be
+// shown, and records a warning.
+func (s *slideSet) AddErrorSlide(zid id.Zid, errMsg string) {
+	sl := newErrorSlide(zid, errMsg)
+	s.seqSlide = append(s.seqSlide, sl)
+	s.setSlide[zid] = sl
+	s.AddWarning(zid, errMsg)
+}
+
+// AddWarning records a zettel that could not be shown.
+func (s *slideSet) AddWarning(zid id.Zid, errMsg string) {
+	w := slideWarning{zid: zid, errMsg: errMsg}
+	if !slices.Contains(s.warnings, w) {
+		s.warnings = append(s.warnings, w)
+	}
+}
+
+// Warnings returns all zettel that could not be shown.
+func (s *slideSet) Warnings() []slideWarning { return s.warnings }
+
+// NonPublic returns all linked zettel that are not shown, because they are
+// not public.
+func (s *slideSet) NonPublic() []id.Zid { return s.nonPublic }
+
 func (s *slideSet) AdditionalSlide(zid id.Zid, sxMeta sz.Meta, sxContent *sx.Pair) {
 	// TODO: if first, add slide with text "additional content"
 	sl := newSlide(zid, sxMeta, sxContent)
 	s.seqSlide = append(s.seqSlide, sl)
 	s.setSlide[zid] = sl
+	s.additional = append(s.additional, zid)
 }
 
+// Additional returns all linked public zettel that were added as slides.
+func (s *slideSet) Additional() []id.Zid { return s.additional }
+
+// IsAdditional returns true, if the given zettel was added as a slide,
+// because it is public and linked from a slide.
+func (s *slideSet) IsAdditional(zid id.Zid) bool { return slices.Contains(s.additional, zid) }
+
 func (s *slideSet) Completion(getZettel getZettelContentFunc, getZettelSexpr sGetZettelFunc) {
 	if s.isCompleted {
 		return
@@ -532,19 +620,28 @@ func (ce *collectEnv) visitZettel(zid id.Zid) {
 	}
 	sxZettel, err := ce.sGetZettel(zid)
 	if err != nil {
-		log.Println("GETS", err)
-		// TODO: add artificial slide with error message / data
+		slog.Warn("Unable to retrieve zettel", "zid", zid, "err", err)
+		ce.mark(zid)
+		if errMsg := errorMessage(err); errMsg == errMsgForbidden {
+			// Like a non-public zettel, it is not shown.
+			ce.s.AddWarning(zid, errMsg)
+		} else {
+			ce.s.AddErrorSlide(zid, errMsg)
+		}
 		return
 	}
 	sxMeta, sxContent := sz.GetMetaContent(sxZettel)
 	if sxMeta == nil || sxContent == nil {
-		// TODO: Add artificial slide with error message
-		log.Println("MECo", zid)
+		slog.Warn("Zettel without metadata or content", "zid", zid)
+		ce.mark(zid)
+		ce.s.AddErrorSlide(zid, errMsgParse)
 		return
 	}
 
 	if vis := sxMeta.GetString(meta.KeyVisibility); vis != meta.ValueVisibilityPublic {
-		// log.Println("VISZ", zid, vis)
+		slog.Debug("Non-public zettel ignored", "zid", zid, "visibility", vis)
+		ce.mark(zid)
+		ce.s.nonPublic = append(ce.s.nonPublic, zid)
 		return
 	}
 	ce.s.AdditionalSlide(zid, sxMeta, sxContent)
@@ -560,13 +657,80 @@ func (ce *collectEnv) visitImage(zid id.Zid, syntax string) {
 
 	data, err := ce.getZettel(zid)
 	if err != nil {
-		log.Println("GETI", err)
-		// TODO: add artificial image with error message / zid
+		slog.Warn("Unable to retrieve image", "zid", zid, "err", err)
+		ce.s.AddWarning(zid, "image "+errorMessage(err))
 		return
 	}
 	ce.s.AddImage(zid, syntax, data)
 }
 
+// slideRef is a reference from the slide set zettel to a slide.
+type slideRef struct {
+	zid    id.Zid
+	broken bool // Referenced zettel does not exist
+}
+
+// getSlideRefs returns the references to slides of a slide set content: for
+// each item of a list, its first link to a zettel outside of nested lists.
+// The items of nested lists are references on their own. These are the
+// references that the ITEMS directive of a query follows.
+func getSlideRefs(sxContent *sx.Pair) []slideRef {
+	var result []slideRef
+	collectSlideRefs(sxContent, &result)
+	return result
+}
+
+func collectSlideRefs(obj sx.Object, result *[]slideRef) {
+	pair, isPair := sx.GetPair(obj)
+	if !isPair || pair == nil {
+		return
+	}
+	if sym, isSymbol := sx.GetSymbol(pair.Car()); isSymbol &&
+		(sym.IsEqualSymbol(zsx.SymListOrdered) || sym.IsEqualSymbol(zsx.SymListUnordered)) {
+		for item := range pair.Tail().Values() {
+			if ref, found := findSlideRef(item); found {
+				*result = append(*result, ref)
+			}
+			collectSlideRefs(item, result)
+		}
+		return
+	}
+	for elem := range pair.Values() {
+		collectSlideRefs(elem, result)
+	}
+}
+
+// findSlideRef returns the first link to a zettel within an object, ignoring
+// nested lists.
+func findSlideRef(obj sx.Object) (slideRef, bool) {
+	pair, isPair := sx.GetPair(obj)
+	if !isPair || pair == nil {
+		return slideRef{}, false
+	}
+	sym, isSymbol := sx.GetSymbol(pair.Car())
+	if isSymbol && (sym.IsEqualSymbol(zsx.SymListOrdered) || sym.IsEqualSymbol(zsx.SymListUnordered)) {
+		return slideRef{}, false
+	}
+	if isSymbol && zsx.SymLink.IsEqualSymbol(sym) {
+		refSym, zidVal := zsx.GetReference(pair.Tail().Tail())
+		broken := sz.SymRefStateBroken.IsEqual(refSym)
+		if !broken && !sz.SymRefStateZettel.IsEqual(refSym) {
+			return slideRef{}, false
+		}
+		zid, err := id.Parse(zidVal)
+		if err != nil {
+			return slideRef{}, false
+		}
+		return slideRef{zid: zid, broken: broken}, true
+	}
+	for elem := range pair.Values() {
+		if ref, found := findSlideRef(elem); found {
+			return ref, true
+		}
+	}
+	return slideRef{}, false
+}
+
 // Utility function to retrieve some slide/slideset metadata.
 
 func getZettelTitleZid(sxMeta sz.Meta, zid id.Zid) *sx.Pair {
@@ -0,0 +1,3 @@
+module zettelstore.de/contrib/server
+
+go 1.26
@@ -0,0 +1,67 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Server.
+//
+// Zettel Server is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetupLogging(t *testing.T) {
+	testcases := []struct {
+		level  string
+		format string
+		ok     bool
+	}{
+		{"info", "text", true},
+		{"debug", "json", true},
+		{"verbose", "text", false},
+		{"info", "xml", false},
+	}
+	for _, tc := range testcases {
+		if err := SetupLogging(tc.level, tc.format); (err == nil) != tc.ok {
+			t.Errorf("SetupLogging(%q, %q): unexpected error %v", tc.level, tc.format, err)
+		}
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	h := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	})
+
+	start := time.Now()
+	WithTimeout(time.Minute, h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !hasDeadline || deadline.Before(start.Add(time.Minute)) {
+		t.Errorf("expected deadline after one minute, but got %v (%v)", deadline.Sub(start), hasDeadline)
+	}
+
+	WithTimeout(0, h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if hasDeadline {
+		t.Errorf("no deadline expected, but got %v", deadline)
+	}
+}
+
+func TestServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Serve(ctx, "127.0.0.1:0", http.NotFoundHandler()); err != nil {
+		t.Errorf("expected clean shutdown, but got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+// genConfig stores all options to generate zettel.
+type genConfig struct {
+	multiplier int
+	numTags    int
+	maxTags    int
+	tagDist    string
+	start, end time.Time
+	modified   float64
+	visibility []weightedValue
+	maxLinks   int
+	slideSets  int
+	slides     int
+	seed       uint64
+}
+
+type weightedValue struct {
+	value  string
+	weight int
+}
+
+// generator creates zettel with random metadata.
+type generator struct {
+	cfg      *genConfig
+	r        *rand.Rand
+	zipf     *rand.Zipf
+	emit     func(*zstest.Zettel) error
+	total    int       // number of zettel to be generated
+	count    int       // number of zettel generated so far
+	step     float64   // average number of seconds between two zettel
+	prev     time.Time // timestamp of the previous zettel
+	zids     []string  // identifier of all ordinary zettel
+	titles   []string  // titles of all ordinary zettel
+	tagWidth int       // number of digits of a tag number
+	visSum   int       // sum of all visibility weights
+}
+
+// generate creates all zettel and calls emit for every one. The ordinary
+// zettel are copies of the markdown sources, slide sets are spread between
+// them. Zettel identifier are increasing timestamps between the start and
+// the end date. It returns the number of generated zettel.
+func generate(cfg *genConfig, sources []*zstest.Zettel, emit func(*zstest.Zettel) error) (int, error) {
+	numOrdinary := cfg.multiplier * len(sources)
+	g := &generator{
+		cfg:      cfg,
+		r:        rand.New(rand.NewPCG(cfg.seed, cfg.seed)),
+		emit:     emit,
+		total:    numOrdinary + cfg.slideSets*(cfg.slides+1),
+		tagWidth: len(strconv.Itoa(cfg.numTags)),
+	}
+	if cfg.numTags > 1 && cfg.tagDist == "zipf" {
+		g.zipf = rand.NewZipf(g.r, 1.1, 1, uint64(cfg.numTags-1))
+	}
+	for _, wv := range cfg.visibility {
+		g.visSum += wv.weight
+	}
+	seconds := cfg.end.Sub(cfg.start).Seconds()
+	if seconds < float64(g.total) {
+		return 0, fmt.Errorf("period between %s and %s is too short for %d zettel",
+			cfg.start.Format(dateLayout), cfg.end.Format(dateLayout), g.total)
+	}
+	g.step = seconds / float64(g.total)
+
+	interval := numOrdinary + 1
+	if cfg.slideSets > 0 {
+		interval = max(1, numOrdinary/cfg.slideSets)
+	}
+	setNo := 0
+	for copyNo := range cfg.multiplier {
+		for _, src := range sources {
+			if err := g.ordinary(src, copyNo); err != nil {
+				return g.count, err
+			}
+			if len(g.zids)%interval == 0 && setNo < cfg.slideSets {
+				setNo++
+				if err := g.slideSet(setNo, sources); err != nil {
+					return g.count, err
+				}
+			}
+		}
+	}
+	for setNo < cfg.slideSets {
+		setNo++
+		if err := g.slideSet(setNo, sources); err != nil {
+			return g.count, err
+		}
+	}
+	return g.count, nil
+}
+
+// nextZid returns the identifier of the next zettel, and its creation time.
+func (g *generator) nextZid() (string, time.Time) {
+	offset := (float64(g.count) + g.r.Float64()) * g.step
+	ts := g.cfg.start.Add(time.Duration(offset) * time.Second)
+	if !ts.After(g.prev) {
+		ts = g.prev.Add(time.Second)
+	}
+	g.prev = ts
+	g.count++
+	return ts.Format(zstest.TimestampLayout), ts
+}
+
+// newZettel creates a zettel with random metadata.
+func (g *generator) newZettel(content, title, role, syntax string) *zstest.Zettel {
+	zid, created := g.nextZid()
+	z := zstest.NewZettel(zid, content,
+		"title", title,
+		"role", role,
+		"syntax", syntax,
+		"created", zid,
+	)
+	if g.r.Float64() < g.cfg.modified {
+		if rest := g.cfg.end.Sub(created); rest > time.Second {
+			modified := created.Add(time.Duration(g.r.Int64N(int64(rest/time.Second))+1) * time.Second)
+			z.Meta["modified"] = modified.Format(zstest.TimestampLayout)
+		}
+	}
+	if tags := g.tags(); tags != "" {
+		z.Meta["tags"] = tags
+	}
+	if vis := g.visibility(); vis != "" {
+		z.Meta["visibility"] = vis
+	}
+	return z
+}
+
+// ordinary creates a copy of a markdown source, with links to zettel created
+// before.
+func (g *generator) ordinary(src *zstest.Zettel, copyNo int) error {
+	title := src.Meta["title"]
+	if copyNo > 0 {
+		title += " " + strconv.Itoa(copyNo+1)
+	}
+	content := src.Content
+	if n := len(g.zids); n > 0 && g.cfg.maxLinks > 0 {
+		if numLinks := g.r.IntN(g.cfg.maxLinks + 1); numLinks > 0 {
+			var sb strings.Builder
+			sb.WriteString(strings.TrimRight(content, "\n"))
+			sb.WriteString("\n\nSee also: ")
+			for i := range numLinks {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				target := g.r.IntN(n)
+				_, _ = fmt.Fprintf(&sb, "[%s](%s)", escapeMarkdown(g.titles[target]), g.zids[target])
+			}
+			sb.WriteByte('\n')
+			content = sb.String()
+		}
+	}
+	z := g.newZettel(content, title, "zettel", "markdown")
+	g.zids = append(g.zids, z.ID)
+	g.titles = append(g.titles, title)
+	return g.emit(z)
+}
+
+// slideSet creates slides with the content of random markdown sources, and
+// a slide set that lists them.
+func (g *generator) slideSet(setNo int, sources []*zstest.Zettel) error {
+	var sb strings.Builder
+	for range g.cfg.slides {
+		src := sources[g.r.IntN(len(sources))]
+		sl := g.newZettel(src.Content, src.Meta["title"], "slide", "markdown")
+		if err := g.emit(sl); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(&sb, "* [[%s]]\n", sl.ID)
+	}
+	z := g.newZettel(sb.String(), "Slide set "+strconv.Itoa(setNo), "slideset", "zmk")
+	z.Meta["sub-title"] = "Generated for load tests"
+	z.Meta["author"] = "Zettel Teststore"
+	return g.emit(z)
+}
+
+// tags returns a random set of tags, separated by space.
+func (g *generator) tags() string {
+	if g.cfg.numTags == 0 || g.cfg.maxTags == 0 {
+		return ""
+	}
+	numTags := g.r.IntN(min(g.cfg.maxTags, g.cfg.numTags) + 1)
+	seen := make(map[int]bool, numTags)
+	var tags []string
+	for attempts := 0; len(tags) < numTags && attempts < 10*numTags; attempts++ {
+		var tagNo int
+		if g.zipf != nil {
+			tagNo = int(g.zipf.Uint64())
+		} else {
+			tagNo = g.r.IntN(g.cfg.numTags)
+		}
+		if !seen[tagNo] {
+			seen[tagNo] = true
+			tags = append(tags, fmt.Sprintf("#tag%0*d", g.tagWidth, tagNo+1))
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+// visibility returns a random visibility value, according to the weights.
+func (g *generator) visibility() string {
+	if g.visSum == 0 {
+		return ""
+	}
+	n := g.r.IntN(g.visSum)
+	for _, wv := range g.cfg.visibility {
+		if n < wv.weight {
+			return wv.value
+		}
+		n -= wv.weight
+	}
+	return ""
+}
+
+// escapeMarkdown escapes all characters of a link text that have a meaning
+// in markdown.
+func escapeMarkdown(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '[', ']', '*', '_', '`', '<', '>':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+// Command createzettel builds a directory of zettel from the markdown files
+// of the "10000" data set, to be used as a box of a Zettelstore for load
+// tests.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+// dateLayout is the layout of dates given on the command line.
+const dateLayout = "2006-01-02"
+
+func main() {
+	var cfg genConfig
+	outDir := flag.String("o", "zettel", "Output directory, must be empty or not existing")
+	flag.IntVar(&cfg.multiplier, "m", 1, "Number of copies of every markdown file")
+	flag.IntVar(&cfg.numTags, "tags", 100, "Number of different tags")
+	flag.IntVar(&cfg.maxTags, "max-tags", 3, "Maximum number of tags per zettel")
+	tagDist := flag.String("tag-dist", "zipf", "Distribution of tags: uniform, zipf")
+	start := flag.String("start", "2020-01-01", "Date of the first zettel")
+	end := flag.String("end", "2025-12-31", "Date of the last zettel")
+	flag.Float64Var(&cfg.modified, "modified", 0.2, "Fraction of zettel with a modification date")
+	visibility := flag.String("visibility", "", "Mix of visibility values, e.g. public=60,login=30,owner=10")
+	flag.IntVar(&cfg.maxLinks, "links", 3, "Maximum number of links to other zettel per zettel")
+	flag.IntVar(&cfg.slideSets, "slidesets", 10, "Number of slide sets")
+	flag.IntVar(&cfg.slides, "slides", 20, "Number of slides per slide set")
+	seed := flag.Uint64("seed", 1, "Seed of the random number generator")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		_, _ = io.WriteString(out, "  [DIR] Directory with markdown files (default: \"files.md\")\n")
+	}
+	flag.Parse()
+
+	srcDir := "files.md"
+	if flag.NArg() > 0 {
+		srcDir = flag.Arg(0)
+	}
+	if err := cfg.parseOptions(*tagDist, *start, *end, *visibility); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg.seed = *seed
+	if err := checkOutDir(*outDir); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sources, err := zstest.LoadMarkdown(srcDir, 0)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unable to read markdown files: %v\n", err)
+		os.Exit(1)
+	}
+	if len(sources) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "No markdown files found in %q\n", srcDir)
+		os.Exit(1)
+	}
+	n, err := generate(&cfg, sources, func(z *zstest.Zettel) error { return z.WriteFile(*outDir) })
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unable to create zettel: %v\n", err)
+		os.Exit(1)
+	}
+	_, _ = fmt.Printf("%d zettel written to %s\n", n, *outDir)
+}
+
+// parseOptions checks and stores the options that are given as strings.
+func (cfg *genConfig) parseOptions(tagDist, start, end, visibility string) error {
+	switch tagDist {
+	case "uniform", "zipf":
+		cfg.tagDist = tagDist
+	default:
+		return fmt.Errorf("unknown tag distribution %q", tagDist)
+	}
+	var err error
+	if cfg.start, err = time.Parse(dateLayout, start); err != nil {
+		return fmt.Errorf("invalid start date %q: %w", start, err)
+	}
+	if cfg.end, err = time.Parse(dateLayout, end); err != nil {
+		return fmt.Errorf("invalid end date %q: %w", end, err)
+	}
+	cfg.end = cfg.end.Add(24*time.Hour - time.Second)
+	if !cfg.start.Before(cfg.end) {
+		return fmt.Errorf("start date %s must be before end date %s", start, end)
+	}
+	if cfg.visibility, err = parseVisibility(visibility); err != nil {
+		return err
+	}
+	switch {
+	case cfg.multiplier < 1:
+		return fmt.Errorf("multiplier must be at least 1, but is %d", cfg.multiplier)
+	case cfg.numTags < 0 || cfg.maxTags < 0 || cfg.maxLinks < 0 || cfg.slideSets < 0 || cfg.slides < 0:
+		return fmt.Errorf("numbers must not be negative")
+	case cfg.modified < 0 || cfg.modified > 1:
+		return fmt.Errorf("fraction of modified zettel must be between 0 and 1, but is %v", cfg.modified)
+	}
+	return nil
+}
+
+// parseVisibility parses a mix of visibility values, like
+// "public=60,login=30,owner=10". The numbers are relative weights.
+func parseVisibility(s string) ([]weightedValue, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var result []weightedValue
+	for elem := range strings.SplitSeq(s, ",") {
+		val, weight, found := strings.Cut(elem, "=")
+		val = strings.TrimSpace(val)
+		switch val {
+		case "public", "creator", "login", "owner", "expert":
+		default:
+			return nil, fmt.Errorf("unknown visibility %q", val)
+		}
+		w := 1
+		if found {
+			var err error
+			if w, err = strconv.Atoi(strings.TrimSpace(weight)); err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight %q for visibility %q", weight, val)
+			}
+		}
+		result = append(result, weightedValue{val, w})
+	}
+	return result, nil
+}
+
+// checkOutDir creates the output directory, if it does not exist. An
+// existing directory must be empty, so that no zettel will be mixed up.
+func checkOutDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return os.MkdirAll(dir, 0o755)
+		}
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory %q is not empty", dir)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+// Command loaddriver sends requests to Zettel Feeds or Zettel Presenter and
+// reports their latency. Optionally, it serves a fake Zettelstore and reports
+// the number of requests it received.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"zettelstore.de/contrib/zstest"
+)
+
+func main() {
+	zettelDir := flag.String("zettel", "", "Directory with zettel of the fake Zettelstore")
+	listenAddress := flag.String("l", ":23123", "Listen address of the fake Zettelstore")
+	latency := flag.Duration("latency", 0, "Simulated latency of the fake Zettelstore")
+	concurrency := flag.Int("c", 4, "Number of concurrent requests")
+	numRequests := flag.Int("n", 100, "Number of requests per URL")
+	warmup := flag.Int("warmup", 1, "Number of requests per URL before measuring")
+	wait := flag.Duration("wait", time.Minute, "Time to wait until all URLs are available")
+	timeout := flag.Duration("t", 30*time.Second, "Timeout of a request")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		_, _ = io.WriteString(out, "  [URL...] URLs to request; without URLs, only the fake Zettelstore is served\n")
+	}
+	flag.Parse()
+	urls := flag.Args()
+	if *zettelDir == "" && len(urls) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var store *zstest.Server
+	if *zettelDir != "" {
+		var err error
+		if store, err = serveStore(*zettelDir, *listenAddress, *latency); err != nil {
+			log.Fatalf("Unable to start fake Zettelstore: %v", err)
+		}
+	}
+	if len(urls) == 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
+		return
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	if err := waitForURLs(client, urls, *wait); err != nil {
+		log.Fatal(err)
+	}
+	for range *warmup {
+		for _, u := range urls {
+			_, _ = request(client, u)
+		}
+	}
+	if store != nil {
+		store.ResetRequests()
+	}
+
+	start := time.Now()
+	results := run(client, urls, *numRequests, *concurrency)
+	elapsed := time.Since(start)
+
+	total := 0
+	for _, u := range urls {
+		res := results[u]
+		total += res.requests
+		res.print(os.Stdout, u)
+	}
+	_, _ = fmt.Printf("\n%d requests in %v, %.1f requests/s\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+	if store != nil {
+		printUpstream(os.Stdout, store.Requests(), total)
+	}
+}
+
+// serveStore starts a fake Zettelstore with the zettel of a directory.
+func serveStore(dir, addr string, latency time.Duration) (*zstest.Server, error) {
+	zettel, err := zstest.LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	store := zstest.NewServer(zettel...)
+	store.SetLatency(latency)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Fake Zettelstore with %d zettel listening on %s", len(zettel), ln.Addr())
+	go func() {
+		if errServe := http.Serve(ln, store); errServe != nil {
+			log.Fatalf("Fake Zettelstore stopped: %v", errServe)
+		}
+	}()
+	return store, nil
+}
+
+// waitForURLs waits until every URL answers without a server error. This
+// allows to start the fake Zettelstore before the server under test.
+func waitForURLs(client *http.Client, urls []string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for _, u := range urls {
+		for {
+			status, err := request(client, u)
+			if err == nil && status < http.StatusInternalServerError {
+				break
+			}
+			if time.Now().After(deadline) {
+				if err == nil {
+					err = errors.New(http.StatusText(status))
+				}
+				return fmt.Errorf("URL %s not available: %w", u, err)
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// request retrieves an URL and discards its content.
+func request(client *http.Client, u string) (int, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
+
+// result stores the measurements of one URL.
+type result struct {
+	requests  int
+	latencies []time.Duration // of requests with a 2xx status
+	errors    int             // requests without a response
+	failed    int             // responses with a non-2xx status
+	status    map[int]int
+}
+
+// run sends n requests to every URL, with the given number of concurrent
+// requests.
+func run(client *http.Client, urls []string, n, concurrency int) map[string]*result {
+	results := make(map[string]*result, len(urls))
+	for _, u := range urls {
+		results[u] = &result{status: map[int]int{}}
+	}
+	jobs := make(chan string)
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+	for range max(concurrency, 1) {
+		wg.Go(func() {
+			for u := range jobs {
+				start := time.Now()
+				status, err := request(client, u)
+				d := time.Since(start)
+				mx.Lock()
+				res := results[u]
+				res.requests++
+				if err != nil {
+					res.errors++
+				} else {
+					res.status[status]++
+					if status >= 200 && status < 300 {
+						res.latencies = append(res.latencies, d)
+					} else {
+						res.failed++
+					}
+				}
+				mx.Unlock()
+			}
+		})
+	}
+	for range n {
+		for _, u := range urls {
+			jobs <- u
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (res *result) print(w io.Writer, u string) {
+	_, _ = fmt.Fprintf(w, "%s\n", u)
+	var sb strings.Builder
+	for _, status := range slices.Sorted(maps.Keys(res.status)) {
+		_, _ = fmt.Fprintf(&sb, " %d:%d", status, res.status[status])
+	}
+	_, _ = fmt.Fprintf(w, "  requests: %d, errors: %d, failed: %d, status:%s\n", res.requests, res.errors, res.failed, sb.String())
+	if len(res.latencies) == 0 {
+		return
+	}
+	slices.Sort(res.latencies)
+	var sum time.Duration
+	for _, d := range res.latencies {
+		sum += d
+	}
+	_, _ = fmt.Fprintf(w, "  latency: min %v, mean %v, p50 %v, p90 %v, p99 %v, max %v\n",
+		res.latencies[0].Round(time.Microsecond),
+		(sum / time.Duration(len(res.latencies))).Round(time.Microsecond),
+		percentile(res.latencies, 50),
+		percentile(res.latencies, 90),
+		percentile(res.latencies, 99),
+		res.latencies[len(res.latencies)-1].Round(time.Microsecond))
+}
+
+// percentile returns the p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p + 99) / 100
+	return sorted[max(idx-1, 0)].Round(time.Microsecond)
+}
+
+func printUpstream(w io.Writer, requests map[string]int, total int) {
+	sum := 0
+	var sb strings.Builder
+	for _, kind := range slices.Sorted(maps.Keys(requests)) {
+		sum += requests[kind]
+		_, _ = fmt.Fprintf(&sb, ", %s %d", kind, requests[kind])
+	}
+	_, _ = fmt.Fprintf(w, "Zettelstore requests: %d%s\n", sum, sb.String())
+	if total > 0 {
+		_, _ = fmt.Fprintf(w, "Zettelstore requests per request: %.2f\n", float64(sum)/float64(total))
+	}
+}
@@ -0,0 +1,3 @@
+module zettelstore.de/contrib/zstest
+
+go 1.26
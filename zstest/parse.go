@@ -0,0 +1,378 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package zstest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The fake Zettelstore understands only a small subset of Zettelmarkup and
+// of Markdown: headings, thematic breaks, lists, regions, code blocks, and
+// paragraphs with links, embeds, endnotes, strong and emphasized text, and
+// literal code. This is enough to test slide splitting and the rendering of
+// zettel content.
+
+// szBlocks returns the content of a zettel as sz block list.
+func (s *Server) szBlocks(z *Zettel) string {
+	switch syntax := z.Syntax(); syntax {
+	case "zmk", "markdown", "md":
+		p := blockParser{s: s, md: syntax != "zmk", lines: strings.Split(z.Content, "\n")}
+		return sxList(append([]string{"BLOCK"}, p.parseBlocks("")...)...)
+	case "none":
+		return sxList("BLOCK")
+	case "plain", "text", "txt", "css", "html", "sxn", "go":
+		return sxList("BLOCK", sxList("VERBATIM-CODE", sxList(), sxString(z.Content)))
+	default:
+		return sxList("BLOCK") // Binary content, e.g. an image
+	}
+}
+
+type blockParser struct {
+	s     *Server
+	md    bool
+	lines []string
+	pos   int
+}
+
+// parseBlocks parses lines until the end marker of a region.
+func (p *blockParser) parseBlocks(endMarker string) []string {
+	var result, para []string
+	flushPara := func() {
+		if len(para) > 0 {
+			result = append(result, sxList(append([]string{"PARA"}, p.s.inlines(strings.Join(para, "\n"))...)...))
+			para = nil
+		}
+	}
+	for p.pos < len(p.lines) {
+		line := strings.TrimRight(p.lines[p.pos], " \t\r")
+		p.pos++
+		if endMarker != "" && line == endMarker {
+			break
+		}
+		if line == "" {
+			flushPara()
+			continue
+		}
+		if block, found := p.parseBlock(line); found {
+			flushPara()
+			result = append(result, block)
+			continue
+		}
+		para = append(para, line)
+	}
+	flushPara()
+	return result
+}
+
+func (p *blockParser) parseBlock(line string) (string, bool) {
+	if level, text, found := p.heading(line); found {
+		text, attrs := cutAttributes(text)
+		slug := strings.ToLower(strings.Join(strings.Fields(text), "-"))
+		elems := []string{"HEADING", strconv.Itoa(level), attrs, sxString(slug), sxString(slug)}
+		return sxList(append(elems, p.s.inlines(text)...)...), true
+	}
+	if strings.HasPrefix(line, "---") {
+		if rest := strings.TrimLeft(line, "-"); rest == "" || strings.HasPrefix(rest, "{") {
+			_, attrs := cutAttributes(rest)
+			return sxList("THEMATIC", attrs), true
+		}
+	}
+	if marker, found := p.listMarker(line); found {
+		return p.parseList(marker, line), true
+	}
+	if rest, found := strings.CutPrefix(line, "```"); found {
+		var code []string
+		for p.pos < len(p.lines) {
+			l := strings.TrimRight(p.lines[p.pos], "\r")
+			p.pos++
+			if strings.HasPrefix(l, "```") {
+				break
+			}
+			code = append(code, l)
+		}
+		attrs := "()"
+		if lang := strings.TrimSpace(rest); lang != "" {
+			attrs = sxList(sxList(sxString(""), ".", sxString(lang)))
+		}
+		return sxList("VERBATIM-CODE", attrs, sxString(strings.Join(code, "\n"))), true
+	}
+	if rest, found := strings.CutPrefix(line, ":::"); found && !p.md {
+		marker := ":::" + strings.Repeat(":", len(rest)-len(strings.TrimLeft(rest, ":")))
+		val := strings.TrimSpace(strings.TrimLeft(rest, ":"))
+		attrs := "()"
+		if val != "" {
+			attrs = sxList(sxList(sxString(""), ".", sxString(val)))
+		}
+		blocks := p.parseBlocks(marker)
+		return sxList("REGION-BLOCK", attrs, sxList(blocks...)), true
+	}
+	return "", false
+}
+
+// heading returns the level and the text of a heading line.
+func (p *blockParser) heading(line string) (int, string, bool) {
+	ch, offset := byte('='), 2
+	if p.md {
+		ch, offset = '#', 0
+	}
+	n := 0
+	for n < len(line) && line[n] == ch {
+		n++
+	}
+	if n <= offset || n-offset > 6 || n >= len(line) || line[n] != ' ' {
+		return 0, "", false
+	}
+	return n - offset, strings.TrimSpace(line[n:]), true
+}
+
+// listMarker returns the marker of a list item line.
+func (p *blockParser) listMarker(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	for _, marker := range []string{"* ", "- ", "# "} {
+		if strings.HasPrefix(trimmed, marker) && (marker != "# " || !p.md) && (marker != "- " || p.md) {
+			return marker, true
+		}
+	}
+	if p.md {
+		if pos := strings.Index(trimmed, ". "); pos > 0 {
+			if _, err := strconv.Atoi(trimmed[:pos]); err == nil {
+				return "1. ", true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *blockParser) parseList(marker, line string) string {
+	sym := "UNORDERED"
+	if marker == "# " || marker == "1. " {
+		sym = "ORDERED"
+	}
+	elems := []string{sym, "()"}
+	for {
+		text := strings.TrimLeft(line, " ")
+		if marker == "1. " {
+			_, text, _ = strings.Cut(text, ". ")
+		} else {
+			text = text[len(marker):]
+		}
+		elems = append(elems, sxList(sxList(append([]string{"PARA"}, p.s.inlines(strings.TrimSpace(text))...)...)))
+		if p.pos >= len(p.lines) {
+			break
+		}
+		next := strings.TrimRight(p.lines[p.pos], " \t\r")
+		if m, found := p.listMarker(next); !found || m != marker {
+			break
+		}
+		line = next
+		p.pos++
+	}
+	return sxList(elems...)
+}
+
+// cutAttributes removes attributes "{...}" from the end of a text and
+// returns them as s-expression. Only the default attribute "-" and a
+// generic value are supported.
+func cutAttributes(text string) (string, string) {
+	if !strings.HasSuffix(text, "}") {
+		return text, "()"
+	}
+	pos := strings.LastIndexByte(text, '{')
+	if pos < 0 {
+		return text, "()"
+	}
+	val := strings.TrimSpace(text[pos+1 : len(text)-1])
+	text = strings.TrimSpace(text[:pos])
+	if val == "" {
+		return text, "()"
+	}
+	if val == "-" {
+		return text, sxList(sxList(sxString("-"), ".", sxString("")))
+	}
+	return text, sxList(sxList(sxString(""), ".", sxString(val)))
+}
+
+// inlines returns the inline elements of a text.
+func (s *Server) inlines(text string) []string {
+	var result []string
+	var sb strings.Builder
+	flushText := func() {
+		if sb.Len() > 0 {
+			result = append(result, sxList("TEXT", sxString(sb.String())))
+			sb.Reset()
+		}
+	}
+	for len(text) > 0 {
+		if elem, rest, found := s.inline(text); found {
+			flushText()
+			result = append(result, elem)
+			text = rest
+			continue
+		}
+		if text[0] == '\n' {
+			flushText()
+			result = append(result, sxList("SOFT"))
+			text = text[1:]
+			continue
+		}
+		sb.WriteByte(text[0])
+		text = text[1:]
+	}
+	flushText()
+	return result
+}
+
+// inline parses an inline element at the start of the text.
+func (s *Server) inline(text string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(text, "[["):
+		if inner, rest, found := cutDelimited(text[2:], "]]"); found {
+			txt, ref := splitRef(inner)
+			return s.link(txt, ref), rest, true
+		}
+	case strings.HasPrefix(text, "{{"):
+		if inner, rest, found := cutDelimited(text[2:], "}}"); found {
+			txt, ref := splitRef(inner)
+			return s.embed(txt, ref), rest, true
+		}
+	case strings.HasPrefix(text, "![") || strings.HasPrefix(text, "["):
+		isEmbed := text[0] == '!'
+		start := 1
+		if isEmbed {
+			start = 2
+		}
+		txt, rest, found := cutDelimited(text[start:], "]")
+		if !found {
+			break
+		}
+		if !strings.HasPrefix(rest, "(") {
+			if note, isNote := strings.CutPrefix(txt, "^"); isNote && !isEmbed {
+				return sxList(append([]string{"ENDNOTE", "()"}, s.inlines(note)...)...), rest, true
+			}
+			break
+		}
+		ref, rest, found := cutDelimited(rest[1:], ")")
+		if !found {
+			break
+		}
+		if isEmbed {
+			return s.embed(txt, ref), rest, true
+		}
+		return s.link(txt, ref), rest, true
+	case strings.HasPrefix(text, "**"):
+		if inner, rest, found := cutDelimited(text[2:], "**"); found && inner != "" {
+			return sxList(append([]string{"FORMAT-STRONG", "()"}, s.inlines(inner)...)...), rest, true
+		}
+	case strings.HasPrefix(text, "__"):
+		if inner, rest, found := cutDelimited(text[2:], "__"); found && inner != "" {
+			return sxList(append([]string{"FORMAT-EMPH", "()"}, s.inlines(inner)...)...), rest, true
+		}
+	case strings.HasPrefix(text, "``"):
+		if inner, rest, found := cutDelimited(text[2:], "``"); found {
+			return sxList("LITERAL-CODE", "()", sxString(inner)), rest, true
+		}
+	case strings.HasPrefix(text, "`"):
+		if inner, rest, found := cutDelimited(text[1:], "`"); found {
+			return sxList("LITERAL-CODE", "()", sxString(inner)), rest, true
+		}
+	}
+	return "", "", false
+}
+
+func cutDelimited(text, end string) (string, string, bool) {
+	inner, rest, found := strings.Cut(text, end)
+	if !found || strings.Contains(inner, "\n\n") {
+		return "", "", false
+	}
+	return inner, rest, true
+}
+
+// splitRef splits the content of a Zettelmarkup link or embed into text and
+// reference.
+func splitRef(inner string) (string, string) {
+	if pos := strings.LastIndexByte(inner, '|'); pos >= 0 {
+		return strings.TrimSpace(inner[:pos]), strings.TrimSpace(inner[pos+1:])
+	}
+	return "", strings.TrimSpace(inner)
+}
+
+// reference returns the s-expression of a reference. References to existing
+// zettel have the state ZETTEL, references to missing zettel are BROKEN.
+func (s *Server) reference(ref string) string {
+	zid, _, _ := strings.Cut(ref, "#")
+	switch {
+	case IsValidZid(zid):
+		if _, found := s.zettel[zid]; found {
+			return sxList("ZETTEL", sxString(ref))
+		}
+		return sxList("BROKEN", sxString(ref))
+	case strings.HasPrefix(ref, "#"):
+		return sxList("SELF", sxString(ref))
+	case strings.Contains(ref, "://") || strings.HasPrefix(ref, "mailto:"):
+		return sxList("EXTERNAL", sxString(ref))
+	}
+	return sxList("HOSTED", sxString(ref))
+}
+
+func (s *Server) link(text, ref string) string {
+	elems := []string{"LINK", "()", s.reference(ref)}
+	if text != "" {
+		elems = append(elems, s.inlines(text)...)
+	}
+	return sxList(elems...)
+}
+
+func (s *Server) embed(text, ref string) string {
+	syntax := ""
+	if z, found := s.zettel[ref]; found {
+		syntax = z.Syntax()
+	} else if pos := strings.LastIndexByte(ref, '.'); pos > 0 && !IsValidZid(ref) {
+		syntax = syntaxExtension(ref[pos:])
+	}
+	elems := []string{"EMBED", "()", s.reference(ref), sxString(syntax)}
+	if text != "" {
+		elems = append(elems, s.inlines(text)...)
+	}
+	return sxList(elems...)
+}
+
+// itemZids returns the identifier of all zettel that are referenced in the
+// list items of the content, in the order of their occurrence. This is the
+// meaning of the ITEMS directive of a query.
+func itemZids(z *Zettel) []string {
+	var result []string
+	p := blockParser{md: z.Syntax() != "zmk"}
+	for line := range strings.Lines(z.Content) {
+		line = strings.TrimRight(line, " \t\r\n")
+		if _, found := p.listMarker(line); !found {
+			continue
+		}
+		for _, start := range []string{"[[", "]("} {
+			if pos := strings.Index(line, start); pos >= 0 {
+				rest := line[pos+2:]
+				end := strings.IndexAny(rest, "])")
+				if end < 0 {
+					continue
+				}
+				_, ref := splitRef(rest[:end])
+				if zid, _, _ := strings.Cut(ref, "#"); IsValidZid(zid) {
+					result = append(result, zid)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,280 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package zstest
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// query is a parsed query of the Zettelstore query language. Only a subset
+// is supported: leading zettel identifier, search terms that compare
+// metadata, full-text words, the directives ITEMS, ORDER, REVERSE, OFFSET,
+// LIMIT, and PICK, and a single action that aggregates a metadata key.
+type query struct {
+	zids   []string
+	items  bool
+	terms  []term
+	order  []orderSpec
+	offset int
+	limit  int
+	action string // Metadata key to aggregate, may be empty
+}
+
+type term struct {
+	key    string // Empty for a full-text search
+	op     byte   // One of '=', ':', '~', '<', '>', '[', ']', '?'
+	negate bool
+	value  string
+}
+
+type orderSpec struct {
+	key     string
+	reverse bool
+}
+
+// errUnsupported is returned for queries that the fake Zettelstore does not
+// understand.
+var errUnsupported = errors.New("unsupported query")
+
+func parseQuery(s string) (*query, error) {
+	q := &query{}
+	words := strings.Fields(s)
+	for len(words) > 0 && IsValidZid(words[0]) {
+		q.zids = append(q.zids, words[0])
+		words = words[1:]
+	}
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch word {
+		case "ITEMS":
+			q.items = true
+		case "ORDER":
+			spec := orderSpec{}
+			if i+1 < len(words) && words[i+1] == "REVERSE" {
+				spec.reverse = true
+				i++
+			}
+			if i+1 >= len(words) {
+				return nil, fmt.Errorf("%w: missing ORDER key", errUnsupported)
+			}
+			i++
+			spec.key = words[i]
+			q.order = append(q.order, spec)
+		case "OFFSET", "LIMIT", "PICK":
+			if i+1 >= len(words) {
+				return nil, fmt.Errorf("%w: missing %s value", errUnsupported, word)
+			}
+			i++
+			n, err := strconv.Atoi(words[i])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: invalid %s value %q", errUnsupported, word, words[i])
+			}
+			switch word {
+			case "OFFSET":
+				q.offset = n
+			default:
+				if q.limit == 0 || (n > 0 && n < q.limit) {
+					q.limit = n
+				}
+			}
+		case "OR", "CONTEXT", "REVERSE", "RANDOM":
+			return nil, fmt.Errorf("%w: %s", errUnsupported, word)
+		default:
+			if strings.HasPrefix(word, "|") {
+				action, err := parseAction(strings.TrimPrefix(word, "|"), words[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				q.action = action
+				return q, nil
+			}
+			q.terms = append(q.terms, parseTerm(word))
+		}
+	}
+	return q, nil
+}
+
+// parseAction returns the metadata key of an aggregate action. The first
+// word may be empty, if the action is separated from the pipe symbol.
+func parseAction(first string, rest []string) (string, error) {
+	words := rest
+	if first != "" {
+		words = append([]string{first}, rest...)
+	}
+	if len(words) != 1 {
+		return "", fmt.Errorf("%w: only one action is supported", errUnsupported)
+	}
+	key := words[0]
+	if strings.IndexFunc(key, func(r rune) bool { return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' }) >= 0 {
+		return "", fmt.Errorf("%w: action %s", errUnsupported, key)
+	}
+	return key, nil
+}
+
+func parseTerm(word string) term {
+	pos := strings.IndexAny(word, "!:=<>~[]?")
+	if pos <= 0 {
+		return term{op: ':', value: strings.ToLower(word)}
+	}
+	t := term{key: strings.ToLower(word[:pos])}
+	rest := word[pos:]
+	if rest[0] == '!' {
+		t.negate = true
+		rest = rest[1:]
+	}
+	if rest == "" {
+		t.op = '?' // "key!" is the same as "key!?"
+		return t
+	}
+	t.op, t.value = rest[0], rest[1:]
+	return t
+}
+
+// match returns true, if the zettel fulfills the search term.
+func (t *term) match(z *Zettel, m map[string]string) bool {
+	if t.key == "" {
+		return t.negate != (strings.Contains(strings.ToLower(m["title"]), t.value) ||
+			strings.Contains(strings.ToLower(z.Content), t.value))
+	}
+	val, found := m[t.key]
+	if t.key == "id" {
+		val, found = z.ID, true
+	}
+	if t.op == '?' {
+		return t.negate != (found && val != "")
+	}
+	if t.value == "" {
+		return t.negate != found
+	}
+	var result bool
+	if isSetKey(t.key) {
+		want := strings.TrimPrefix(strings.ToLower(t.value), "#")
+		for _, elem := range strings.Fields(val) {
+			if t.compare(strings.TrimPrefix(strings.ToLower(elem), "#"), want) {
+				result = true
+				break
+			}
+		}
+	} else {
+		result = t.compare(strings.ToLower(val), strings.ToLower(t.value))
+	}
+	return t.negate != result
+}
+
+func (t *term) compare(val, want string) bool {
+	switch t.op {
+	case '=', ':':
+		return val == want
+	case '~':
+		return strings.Contains(val, want)
+	case '[':
+		return strings.HasPrefix(val, want)
+	case ']':
+		return strings.HasSuffix(val, want)
+	case '<':
+		return val < want
+	case '>':
+		return val > want
+	}
+	return false
+}
+
+func isSetKey(key string) bool {
+	switch key {
+	case "tags", "back", "forward", "folge", "precursor", "dead":
+		return true
+	}
+	return false
+}
+
+// apply returns the zettel that fulfill the query, in the requested order.
+// Zettel are given with their metadata, as visible to the client.
+func (q *query) apply(zettel []*Zettel, metas map[string]map[string]string) []*Zettel {
+	var result []*Zettel
+	for _, z := range zettel {
+		if matchAll(q.terms, z, metas[z.ID]) {
+			result = append(result, z)
+		}
+	}
+	if len(q.zids) == 0 {
+		// Default order, and order of zettel with equal values: newest first
+		slices.SortFunc(result, func(a, b *Zettel) int { return cmp.Compare(b.ID, a.ID) })
+	}
+	for i := len(q.order) - 1; i >= 0; i-- {
+		spec := q.order[i]
+		slices.SortStableFunc(result, func(a, b *Zettel) int {
+			va, vb := metas[a.ID][spec.key], metas[b.ID][spec.key]
+			if spec.key == "id" {
+				va, vb = a.ID, b.ID
+			}
+			if spec.reverse {
+				return cmp.Compare(vb, va)
+			}
+			return cmp.Compare(va, vb)
+		})
+	}
+	if q.offset > 0 {
+		if q.offset >= len(result) {
+			return nil
+		}
+		result = result[q.offset:]
+	}
+	if q.limit > 0 && q.limit < len(result) {
+		result = result[:q.limit]
+	}
+	return result
+}
+
+// aggregate returns the values of the action key, together with the zettel
+// that have this value, in the format of a plain text query result.
+func (q *query) aggregate(result []*Zettel, metas map[string]map[string]string) string {
+	agg := map[string][]string{}
+	for _, z := range result {
+		val := metas[z.ID][q.action]
+		if val == "" {
+			continue
+		}
+		values := []string{val}
+		if isSetKey(q.action) {
+			values = strings.Fields(val)
+		}
+		for _, v := range values {
+			agg[v] = append(agg[v], z.ID)
+		}
+	}
+	var sb strings.Builder
+	for _, v := range slices.Sorted(maps.Keys(agg)) {
+		sb.WriteString(v)
+		for _, zid := range agg[v] {
+			sb.WriteString(" " + zid)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func matchAll(terms []term, z *Zettel, m map[string]string) bool {
+	for i := range terms {
+		if !terms[i].match(z, m) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,432 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package zstest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Version is the version of the fake Zettelstore.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// DefaultVersion is the version reported by a new server.
+var DefaultVersion = Version{Major: 2, Minor: 0, Patch: 0}
+
+// tokenLifetime is the number of seconds an access token is valid.
+const tokenLifetime = 600
+
+// Server is a fake Zettelstore. It implements the subset of the web API that
+// is used by the client of Zettelstore: version, authentication, command
+// "authenticated", queries, metadata, content, and evaluated sz of zettel.
+//
+// If no user is added, every zettel is readable. Otherwise, anonymous clients
+// may read only zettel with visibility "public", and authenticated users may
+// read all zettel.
+//
+// Server is an http.Handler; use httptest.NewServer to serve it.
+type Server struct {
+	mx       sync.RWMutex
+	zettel   map[string]*Zettel
+	users    map[string]string // user name, password
+	tokens   map[string]string // token, user name
+	version  Version
+	latency  time.Duration
+	requests map[string]int // kind of request, number
+}
+
+// NewServer creates a new fake Zettelstore with the given zettel.
+func NewServer(zettel ...*Zettel) *Server {
+	s := &Server{
+		zettel:   map[string]*Zettel{},
+		users:    map[string]string{},
+		tokens:   map[string]string{},
+		version:  DefaultVersion,
+		requests: map[string]int{},
+	}
+	s.Add(zettel...)
+	return s
+}
+
+// Add adds zettel to the store, replacing zettel with the same identifier.
+func (s *Server) Add(zettel ...*Zettel) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, z := range zettel {
+		s.zettel[z.ID] = z
+	}
+}
+
+// Remove deletes a zettel.
+func (s *Server) Remove(zid string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.zettel, zid)
+}
+
+// AddUser adds a user that is allowed to authenticate. Adding a user enables
+// access control.
+func (s *Server) AddUser(name, password string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.users[name] = password
+}
+
+// SetVersion changes the version reported to clients.
+func (s *Server) SetVersion(v Version) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.version = v
+}
+
+// SetLatency delays every response, to simulate a remote Zettelstore.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.latency = d
+}
+
+// SetApplication registers a zettel for an application, like the
+// configuration zettel of an application, within the application directory.
+func (s *Server) SetApplication(app, zid string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	dir, found := s.zettel[ZidAppDirectory]
+	if !found {
+		dir = NewZettel(ZidAppDirectory, "",
+			"title", "Zettelstore Application Directory",
+			"role", "configuration",
+			"syntax", "none",
+			"visibility", "login",
+		)
+		s.zettel[ZidAppDirectory] = dir
+	}
+	dir.Meta[app+"-zid"] = zid
+}
+
+// Requests returns the number of requests since the last reset, by kind of
+// request: "version", "auth", "command", "query", "meta", "content",
+// "zettel", and "sz".
+func (s *Server) Requests() map[string]int {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return maps.Clone(s.requests)
+}
+
+// ReportRequests reports the number of requests per operation of a
+// benchmark as metric "upstream/op". It must be called after the benchmark
+// loop, the counters should be reset before it.
+func (s *Server) ReportRequests(b *testing.B) {
+	total := 0
+	for _, n := range s.Requests() {
+		total += n
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "upstream/op")
+}
+
+// ResetRequests sets all request counters to zero.
+func (s *Server) ResetRequests() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	clear(s.requests)
+}
+
+func (s *Server) count(kind string) time.Duration {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.requests[kind]++
+	return s.latency
+}
+
+// ServeHTTP dispatches requests of the web API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/x" && r.Method == http.MethodGet:
+		s.serveVersion(w)
+	case path == "/x" && r.Method == http.MethodPost:
+		s.serveCommand(w, r)
+	case path == "/a" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		s.serveAuth(w, r)
+	case path == "/z" && r.Method == http.MethodGet:
+		s.serveQuery(w, r)
+	case strings.HasPrefix(path, "/z/") && r.Method == http.MethodGet:
+		s.serveZettel(w, r, path[3:])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) delay(kind string) {
+	if latency := s.count(kind); latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+func writeSx(w http.ResponseWriter, sx string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, sx)
+}
+
+func (s *Server) serveVersion(w http.ResponseWriter) {
+	s.delay("version")
+	s.mx.RLock()
+	v := s.version
+	s.mx.RUnlock()
+	writeSx(w, sxList(strconv.Itoa(v.Major), strconv.Itoa(v.Minor), strconv.Itoa(v.Patch),
+		sxString("zstest"), sxString("")))
+}
+
+func (s *Server) serveCommand(w http.ResponseWriter, r *http.Request) {
+	s.delay("command")
+	switch cmd := r.URL.Query().Get("cmd"); cmd {
+	case "authenticated":
+		if _, ok := s.user(r); !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, fmt.Sprintf("Unknown command %q", cmd), http.StatusBadRequest)
+	}
+}
+
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request) {
+	s.delay("auth")
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		if user, authenticated := s.user(r); authenticated && user != "" {
+			name, ok = user, true // Renew token
+		}
+	} else {
+		s.mx.RLock()
+		pw, found := s.users[name]
+		s.mx.RUnlock()
+		ok = found && pw == password
+	}
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="Default"`)
+		http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	token := rand.Text()
+	s.mx.Lock()
+	s.tokens[token] = name
+	s.mx.Unlock()
+	writeSx(w, sxList(sxString("Bearer"), sxString(token), strconv.Itoa(tokenLifetime)))
+}
+
+// user returns the name of the authenticated user, and whether the request
+// is allowed. If access control is disabled, every request is allowed.
+func (s *Server) user(r *http.Request) (string, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		if name, valid := s.tokens[token]; valid {
+			return name, true
+		}
+	}
+	return "", len(s.users) == 0
+}
+
+// canRead returns true, if the zettel is readable for the user.
+func (s *Server) canRead(z *Zettel, user string) bool {
+	return len(s.users) == 0 || user != "" || z.Meta["visibility"] == "public"
+}
+
+// visibleMeta returns the metadata of a zettel, together with computed
+// metadata. As in Zettelstore, "published" is the value of "published",
+// "modified", or "created", or the identifier, if it is a timestamp.
+func visibleMeta(z *Zettel) map[string]string {
+	m := maps.Clone(z.Meta)
+	if m == nil {
+		m = map[string]string{}
+	}
+	if m["published"] == "" {
+		for _, key := range []string{"modified", "created"} {
+			if val := m[key]; isTimestamp(val) {
+				m["published"] = val
+				break
+			}
+		}
+		if m["published"] == "" && isTimestamp(z.ID) {
+			m["published"] = z.ID
+		}
+	}
+	return m
+}
+
+func isTimestamp(s string) bool {
+	_, err := time.Parse(TimestampLayout, s)
+	return err == nil
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	s.delay("query")
+	user, _ := s.user(r)
+	vals := r.URL.Query()
+	qs := strings.Join(vals["q"], " ")
+	q, err := parseQuery(qs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	var candidates []*Zettel
+	if len(q.zids) == 0 {
+		for _, zid := range slices.Sorted(maps.Keys(s.zettel)) {
+			candidates = append(candidates, s.zettel[zid])
+		}
+	} else {
+		for _, zid := range q.zids {
+			if z, found := s.zettel[zid]; found {
+				candidates = append(candidates, z)
+			}
+		}
+	}
+	if q.items {
+		var items []*Zettel
+		for _, z := range candidates {
+			if !s.canRead(z, user) {
+				continue
+			}
+			for _, zid := range itemZids(z) {
+				if item, found := s.zettel[zid]; found {
+					items = append(items, item)
+				}
+			}
+		}
+		candidates = items
+	}
+	metas := make(map[string]map[string]string, len(candidates))
+	visible := candidates[:0:0]
+	for _, z := range candidates {
+		if s.canRead(z, user) {
+			visible = append(visible, z)
+			metas[z.ID] = visibleMeta(z)
+		}
+	}
+	result := q.apply(visible, metas)
+
+	if q.action != "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, q.aggregate(result, metas))
+		return
+	}
+	if vals.Get("enc") != "data" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, z := range result {
+			_, _ = fmt.Fprintf(w, "%s %s\n", z.ID, z.Meta["title"])
+		}
+		return
+	}
+	elems := make([]string, 0, len(result)+1)
+	elems = append(elems, "list")
+	for _, z := range result {
+		elems = append(elems, sxList("zettel", sxList("id", zidNumber(z.ID)), dataMeta(metas[z.ID]), sxList("rights", "2")))
+	}
+	writeSx(w, sxList("meta-list", sxList("query", sxString(qs)), sxList("human", sxString(qs)), sxList(elems...)))
+}
+
+func zidNumber(zid string) string {
+	n, err := strconv.ParseUint(zid, 10, 64)
+	if err != nil {
+		return "0"
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+func (s *Server) serveZettel(w http.ResponseWriter, r *http.Request, zid string) {
+	vals := r.URL.Query()
+	enc, part := vals.Get("enc"), vals.Get("part")
+	if part == "" {
+		part = "content"
+		if enc != "" {
+			part = "zettel"
+		}
+	}
+	kind := part
+	if enc == "sz" {
+		kind = "sz"
+	}
+	s.delay(kind)
+	user, _ := s.user(r)
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	z, found := s.zettel[zid]
+	if !found {
+		http.Error(w, "Zettel not found", http.StatusNotFound)
+		return
+	}
+	if !s.canRead(z, user) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	m := visibleMeta(z)
+
+	switch enc {
+	case "":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		switch part {
+		case "content":
+			_, _ = io.WriteString(w, z.Content)
+		case "meta", "zettel":
+			for _, key := range slices.Sorted(maps.Keys(m)) {
+				_, _ = fmt.Fprintf(w, "%s: %s\n", key, m[key])
+			}
+			if part == "zettel" {
+				_, _ = fmt.Fprintf(w, "\n%s", z.Content)
+			}
+		default:
+			http.Error(w, "Unknown part", http.StatusBadRequest)
+		}
+	case "data":
+		switch part {
+		case "meta":
+			writeSx(w, sxList("list", dataMeta(m), sxList("rights", "2")))
+		case "zettel":
+			writeSx(w, sxList("zettel", sxList("id", zidNumber(zid)), dataMeta(m), sxList("rights", "2"),
+				sxList("encoding", sxString("")), sxList("content", sxString(z.Content))))
+		default:
+			http.Error(w, "Unknown part", http.StatusBadRequest)
+		}
+	case "sz":
+		switch part {
+		case "meta":
+			writeSx(w, s.szMeta(m))
+		case "content":
+			writeSx(w, s.szBlocks(z))
+		case "zettel":
+			writeSx(w, sxList(s.szMeta(m), s.szBlocks(z)))
+		default:
+			http.Error(w, "Unknown part", http.StatusBadRequest)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported encoding %q", enc), http.StatusBadRequest)
+	}
+}
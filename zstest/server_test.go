@@ -0,0 +1,174 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package zstest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, srv *httptest.Server, path, token string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func testZettel() []*Zettel {
+	return []*Zettel{
+		NewZettel("20250101000000", "=== Intro\nHello [[World|20250102000000]].", "title", "Intro", "role", "zettel", "tags", "#a #b", "visibility", "public"),
+		NewZettel("20250102000000", "Secret", "title", "World", "role", "zettel", "tags", "#b"),
+		NewZettel("20250103000000", "* [[20250101000000]]\n* [[Second|20250102000000]]\n", "title", "Set", "role", "slideset", "visibility", "public"),
+	}
+}
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(NewServer(testZettel()...))
+	defer srv.Close()
+
+	testcases := []struct {
+		query string
+		exp   string
+	}{
+		{"", "20250103000000 Set\n20250102000000 World\n20250101000000 Intro\n"},
+		{"tags:#b ORDER id", "20250101000000 Intro\n20250102000000 World\n"},
+		{"tags:a", "20250101000000 Intro\n"},
+		{"role!=slideset LIMIT 1", "20250102000000 World\n"},
+		{"role!=slideset OFFSET 1", "20250101000000 Intro\n"},
+		{"20250103000000 ITEMS", "20250101000000 Intro\n20250102000000 World\n"},
+		{"hello", "20250101000000 Intro\n"},
+		{"visibility=public ORDER REVERSE published", "20250103000000 Set\n20250101000000 Intro\n"},
+	}
+	for _, tc := range testcases {
+		status, got := get(t, srv, "/z?q="+url.QueryEscape(tc.query), "")
+		if status != http.StatusOK || got != tc.exp {
+			t.Errorf("query %q: expected %q, but got %d %q", tc.query, tc.exp, status, got)
+		}
+	}
+	if status, _ := get(t, srv, "/z?q="+url.QueryEscape("| KEYS"), ""); status != http.StatusBadRequest {
+		t.Errorf("action should be rejected, but got %d", status)
+	}
+	for _, tc := range []struct{ query, exp string }{
+		{"| tags", "#a 20250101000000\n#b 20250102000000 20250101000000\n"},
+		{"tags:a |role", "zettel 20250101000000\n"},
+	} {
+		if status, got := get(t, srv, "/z?q="+url.QueryEscape(tc.query), ""); status != http.StatusOK || got != tc.exp {
+			t.Errorf("aggregate %q: expected %q, but got %d %q", tc.query, tc.exp, status, got)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	s := NewServer(testZettel()...)
+	s.AddUser("owner", "secret")
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	if status, got := get(t, srv, "/z", ""); status != http.StatusOK || got != "20250103000000 Set\n20250101000000 Intro\n" {
+		t.Errorf("anonymous should see only public zettel, but got %d %q", status, got)
+	}
+	if status, _ := get(t, srv, "/z/20250102000000", ""); status != http.StatusForbidden {
+		t.Errorf("expected forbidden, but got %d", status)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("owner", "secret")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	fields := strings.Fields(strings.Trim(string(data), "()"))
+	if resp.StatusCode != http.StatusOK || len(fields) != 3 || fields[0] != `"Bearer"` {
+		t.Fatalf("authentication failed: %d %q", resp.StatusCode, data)
+	}
+	token := strings.Trim(fields[1], `"`)
+	if status, got := get(t, srv, "/z/20250102000000", token); status != http.StatusOK || got != "Secret" {
+		t.Errorf("expected content, but got %d %q", status, got)
+	}
+	if got := s.Requests()["content"]; got != 2 {
+		t.Errorf("expected 2 content requests, but got %d", got)
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	srv := httptest.NewServer(NewServer(testZettel()...))
+	defer srv.Close()
+
+	testcases := []struct {
+		path string
+		exp  string
+	}{
+		{"/x", `(2 0 0 "zstest" "")`},
+		{"/z/20250102000000?enc=data&part=meta",
+			`(list (meta (published "20250102000000") (role "zettel") (tags "#b") (title "World")) (rights 2))`},
+		{"/z/20250102000000?enc=sz&part=meta",
+			`(META (TIMESTAMP (quote published) "20250102000000") (WORD (quote role) "zettel") ` +
+				`(TAG-SET (quote tags) ("#b")) (ZETTELMARKUP (quote title) ((TEXT "World"))))`},
+		{"/z/20250101000000?enc=sz&part=content",
+			`(BLOCK (HEADING 1 () "intro" "intro" (TEXT "Intro")) ` +
+				`(PARA (TEXT "Hello ") (LINK () (ZETTEL "20250102000000") (TEXT "World")) (TEXT ".")))`},
+		{"/z?q=tags%3Ab&enc=data",
+			`(meta-list (query "tags:b") (human "tags:b") (list ` +
+				`(zettel (id 20250102000000) (meta (published "20250102000000") (role "zettel") (tags "#b") (title "World")) (rights 2)) ` +
+				`(zettel (id 20250101000000) (meta (published "20250101000000") (role "zettel") (tags "#a #b") (title "Intro") (visibility "public")) (rights 2))))`},
+	}
+	for _, tc := range testcases {
+		status, got := get(t, srv, tc.path, "")
+		if status != http.StatusOK || got != tc.exp {
+			t.Errorf("%s:\nexpected %s\nbut got  %d %s", tc.path, tc.exp, status, got)
+		}
+	}
+}
+
+func TestLoadMarkdown(t *testing.T) {
+	zs, err := LoadMarkdown("../10000/files.md", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zs) != 3 {
+		t.Fatalf("expected 3 zettel, but got %d", len(zs))
+	}
+	if z := zs[1]; z.ID != "19800101000100" || z.Meta["title"] != "A cappella surgical gown" || z.Meta["syntax"] != "markdown" {
+		t.Errorf("unexpected zettel %v %v", z.ID, z.Meta)
+	}
+	s := NewServer(zs...)
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if sz := s.szBlocks(zs[0]); !strings.HasPrefix(sz, `(BLOCK (HEADING 1 () "for-really-this-to-queer"`) {
+		t.Errorf("unexpected sz: %.200s", sz)
+	}
+}
@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package zstest
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The fake Zettelstore writes s-expressions as text, so that it does not
+// depend on the packages it is used to test.
+
+// sxString returns the s-expression of a string.
+func sxString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if ch >= ' ' {
+				sb.WriteRune(ch)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// sxList returns a list of s-expressions.
+func sxList(elems ...string) string { return "(" + strings.Join(elems, " ") + ")" }
+
+// dataMeta returns the metadata in the "data" encoding.
+func dataMeta(m map[string]string) string {
+	elems := []string{"meta"}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		elems = append(elems, sxList(key, sxString(m[key])))
+	}
+	return sxList(elems...)
+}
+
+// Types of metadata values, as used by the sz encoding.
+var metaTypes = map[string]string{
+	"author":        "STRING",
+	"back":          "ZID-SET",
+	"box-number":    "NUMBER",
+	"copyright":     "STRING",
+	"created":       "TIMESTAMP",
+	"credential":    "CREDENTIAL",
+	"dead":          "ZID-SET",
+	"folge":         "ZID-SET",
+	"forward":       "ZID-SET",
+	"lang":          "WORD",
+	"license":       "EMPTY-STRING",
+	"modified":      "TIMESTAMP",
+	"precursor":     "ZID-SET",
+	"published":     "TIMESTAMP",
+	"role":          "WORD",
+	"slide-role":    "WORD",
+	"slide-title":   "ZETTELMARKUP",
+	"sub-title":     "ZETTELMARKUP",
+	"summary":       "ZETTELMARKUP",
+	"syntax":        "WORD",
+	"tags":          "TAG-SET",
+	"title":         "ZETTELMARKUP",
+	"url":           "URL",
+	"visibility":    "WORD",
+	"css-zid":       "ZID",
+	"home-zettel":   "ZID",
+	"slideset-role": "WORD",
+}
+
+func metaType(key string) string {
+	if typ, found := metaTypes[key]; found {
+		return typ
+	}
+	switch {
+	case strings.HasSuffix(key, "-url"):
+		return "URL"
+	case strings.HasSuffix(key, "-zid"):
+		return "ZID"
+	case strings.HasSuffix(key, "-title"):
+		return "ZETTELMARKUP"
+	}
+	return "EMPTY-STRING"
+}
+
+// szMeta returns the metadata in the "sz" encoding. Values of type
+// zettelmarkup are lists of inline elements, values of set types are lists
+// of strings.
+func (s *Server) szMeta(m map[string]string) string {
+	elems := []string{"META"}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		typ := metaType(key)
+		var val string
+		switch typ {
+		case "ZETTELMARKUP":
+			val = sxList(s.inlines(m[key])...)
+		case "ZID-SET", "TAG-SET":
+			var words []string
+			for _, word := range strings.Fields(m[key]) {
+				words = append(words, sxString(word))
+			}
+			val = sxList(words...)
+		default:
+			val = sxString(m[key])
+		}
+		elems = append(elems, sxList(typ, sxList("quote", key), val))
+	}
+	return sxList(elems...)
+}
@@ -0,0 +1,245 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of Zettel Teststore.
+//
+// Zettel Teststore is licensed under the latest version of the EUPL (European
+// Union Public License). Please see file LICENSE.txt for your rights and
+// obligations under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+// Package zstest provides a fake Zettelstore to test clients of its web API.
+package zstest
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Some well-known zettel identifier.
+const (
+	ZidConfiguration = "00000000000100"
+	ZidAppDirectory  = "00000000090000"
+	ZidDefaultHome   = "00010000000000"
+)
+
+// TimestampLayout is the layout of timestamps and of zettel identifier.
+const TimestampLayout = "20060102150405"
+
+// Zettel is a zettel of the fake Zettelstore.
+type Zettel struct {
+	ID      string            // Zettel identifier, 14 digits
+	Meta    map[string]string // Metadata, without the identifier
+	Content string
+}
+
+// NewZettel creates a new zettel. Metadata is given as pairs of key and value.
+func NewZettel(zid, content string, keyValues ...string) *Zettel {
+	m := make(map[string]string, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		m[keyValues[i]] = keyValues[i+1]
+	}
+	return &Zettel{ID: zid, Meta: m, Content: content}
+}
+
+// Syntax returns the syntax of the zettel content.
+func (z *Zettel) Syntax() string {
+	if syntax := z.Meta["syntax"]; syntax != "" {
+		return syntax
+	}
+	return "zmk"
+}
+
+// IsValidZid returns true, if the string is a zettel identifier.
+func IsValidZid(s string) bool {
+	if len(s) != 14 || s == "00000000000000" {
+		return false
+	}
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseZettel parses a zettel in the format of a Zettelstore directory box:
+// lines of metadata "key: value", an empty line, and the content.
+func ParseZettel(zid string, data []byte) *Zettel {
+	m, rest := parseMeta(data)
+	delete(m, "id")
+	return &Zettel{ID: zid, Meta: m, Content: string(rest)}
+}
+
+func parseMeta(data []byte) (map[string]string, []byte) {
+	m := map[string]string{}
+	for len(data) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte{'\n'})
+		data = rest
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+		key, val, found := bytes.Cut(line, []byte{':'})
+		if !found {
+			continue
+		}
+		key = bytes.ToLower(bytes.TrimSpace(key))
+		val = bytes.TrimSpace(val)
+		if prev, isSet := m[string(key)]; isSet && len(val) > 0 {
+			m[string(key)] = prev + " " + string(val)
+		} else {
+			m[string(key)] = string(val)
+		}
+	}
+	return m, data
+}
+
+// LoadDir reads all zettel of a Zettelstore directory box. A zettel is
+// stored either in one file "ZID.zettel", or in a content file "ZID NAME.EXT"
+// together with a metadata file "ZID NAME" or "ZID.meta". Files with other
+// names are ignored.
+func LoadDir(dir string) ([]*Zettel, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byZid := map[string]*Zettel{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || len(name) < 14 || !IsValidZid(name[:14]) {
+			continue
+		}
+		zid := name[:14]
+		data, errRead := os.ReadFile(filepath.Join(dir, name))
+		if errRead != nil {
+			return nil, errRead
+		}
+		z, found := byZid[zid]
+		if !found {
+			z = &Zettel{ID: zid, Meta: map[string]string{}}
+			byZid[zid] = z
+		}
+		ext := filepath.Ext(name[14:])
+		switch ext {
+		case ".zettel":
+			pz := ParseZettel(zid, data)
+			z.Content = pz.Content
+			for k, v := range pz.Meta {
+				z.Meta[k] = v
+			}
+		case "", ".meta":
+			m, _ := parseMeta(data)
+			delete(m, "id")
+			for k, v := range m {
+				z.Meta[k] = v
+			}
+		default:
+			z.Content = string(data)
+			if _, hasSyntax := z.Meta["syntax"]; !hasSyntax {
+				z.Meta["syntax"] = syntaxExtension(ext)
+			}
+		}
+	}
+	result := make([]*Zettel, 0, len(byZid))
+	for _, zid := range slices.Sorted(maps.Keys(byZid)) {
+		result = append(result, byZid[zid])
+	}
+	return result, nil
+}
+
+func syntaxExtension(ext string) string {
+	switch ext = strings.TrimPrefix(ext, "."); ext {
+	case "md":
+		return "markdown"
+	case "txt":
+		return "plain"
+	}
+	return ext
+}
+
+// LoadMarkdown reads up to n markdown files of a directory, such as the
+// "files.md" directory of the "10000" data set. A value of n less than one
+// reads all files. Like the script "create_zettel.py", the title is derived
+// from the file name, and identifiers start at 19800101000000, increasing by
+// one minute for every file. Files are read in the order of their names.
+func LoadMarkdown(dir string, n int) ([]*Zettel, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Zettel
+	ts := time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, entry := range entries {
+		if n > 0 && len(result) >= n {
+			break
+		}
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".md" {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(dir, name))
+		if errRead != nil {
+			return nil, errRead
+		}
+		zid := ts.Format(TimestampLayout)
+		stem := strings.ReplaceAll(strings.TrimSuffix(name, ".md"), ".", "_")
+		result = append(result, NewZettel(zid, string(data),
+			"title", capitalize(stem),
+			"role", "zettel",
+			"syntax", "markdown",
+			"created", zid,
+		))
+		ts = ts.Add(time.Minute)
+	}
+	return result, nil
+}
+
+// capitalize returns the string with the first letter in upper case and all
+// other letters in lower case, as Python's str.capitalize does.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return strings.ToUpper(string(r)) + strings.ToLower(s[size:])
+}
+
+// WriteDir writes the zettel into a directory, using one file "ZID.zettel"
+// for every zettel. The directory can be used as a box of a real Zettelstore.
+func WriteDir(dir string, zettel []*Zettel) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, z := range zettel {
+		if err := z.WriteFile(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the zettel into the file "ZID.zettel" of an existing
+// directory.
+func (z *Zettel) WriteFile(dir string) error {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "id: %s\n", z.ID)
+	for _, key := range slices.Sorted(maps.Keys(z.Meta)) {
+		_, _ = fmt.Fprintf(&buf, "%s: %s\n", key, z.Meta[key])
+	}
+	if z.Content != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(z.Content)
+	}
+	return os.WriteFile(filepath.Join(dir, z.ID+".zettel"), buf.Bytes(), 0o644)
+}